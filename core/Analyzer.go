@@ -2,25 +2,23 @@ package core
 
 import (
   "fmt"
-  "strconv"
-  "strings"
+  "encoding/hex"
   "io/ioutil"
   "debug/elf"
   "regexp"
-  "encoding/hex"
-  "os"
-  "os/exec"
-  "syscall"
-  "bytes"
 
   "jelf/core/state"
-  "jelf/core/info"
+  "jelf/core/debug"
   "jelf/core/misc"
   "jelf/core/err"
 )
 
 type Analyzer struct {
   state.State
+
+  term *misc.Term
+  debugger *debug.Debugger
+  core *debug.Core
 }
 
 func NewAnalyzer(path string) (*Analyzer, error) {
@@ -30,14 +28,26 @@ func NewAnalyzer(path string) (*Analyzer, error) {
     return nil, err
   }
 
+  if file.Type == elf.ET_CORE {
+    if analyzer, err := NewCoreAnalyzer(path, file); err == nil {
+      return analyzer, nil
+    }
+  }
+
   state := state.State {
     Path: path, File: file}
 
+  state.LoadMappings()
+
+  state.Address = file.Entry
+
   return &Analyzer{
-    state}, nil
+    State: state, debugger: debug.NewDebugger(path, file)}, nil
 }
 
 func (p *Analyzer) Analyze() {
+  p.Strings = nil
+
   symbols, err := p.File.Symbols()
 
   if err == nil {
@@ -67,32 +77,12 @@ func (p *Analyzer) Analyze() {
     }
   }
 
-  p.Analyzed = true
-
+  p.LoadDynamic()
+  p.LoadRelocations()
+  p.LoadPlt()
+  p.LoadLines()
 
-
-  // process dyn func names
-  for _, section := range p.Sections {
-    if section.Name == ".dynstr" {
-      data, _ := section.Data()
-      texts := bytes.Split(data, []byte{0x00})
-
-      for n, text := range texts {
-        fmt.Println(n, ":", string(text))
-      }
-    }
-  }
-
-  for _, section := range p.Sections {
-    if section.Name == ".rela.dyn" {
-      data, _ := section.Data()
-
-      fmt.Println(len(data))
-      for i:=0; i<len(data); i+=24 {
-        fmt.Printf("%d : %s", i/24, hex.Dump(data[i:i+24]))
-      }
-    }
-  }
+  p.Analyzed = true
 }
 
 func (p *Analyzer) ShowStrings() {
@@ -102,45 +92,54 @@ func (p *Analyzer) ShowStrings() {
     return
   }
 
+  if p.Output == "json" {
+    misc.ShowJson(append([]string{}, p.Strings...))
+
+    return
+  }
+
   for i:=0; i<len(p.Strings); i++ {
     fmt.Println(p.Strings[i])
   }
 }
 
-func (p *Analyzer) RunProcess(args []string) {
-  cmd := exec.Command(p.Path, args...)
-
-  cmd.Stdin = os.Stdin
-  cmd.Stdout = os.Stdout
-  cmd.Stderr = os.Stderr
-
-  cmd.SysProcAttr = &syscall.SysProcAttr{
-    Ptrace: true}
+type Dump struct {
+  Address uint64 `json:"address"`
+  Bytes string `json:"bytes"`
+  ZeroFill uint64 `json:"zero_fill"`
+  ZeroFillSection string `json:"zero_fill_section,omitempty"`
+}
 
-  cmd.Start()
-  cmd.Wait()
+func (p *Analyzer) GetDump(address, length uint64) (Dump, error) {
+  result := Dump{
+    Address: address}
 
-  fmt.Println(cmd.Process.Pid)
+  data, e := p.ReadVirtual(address, length)
 
-  var regs syscall.PtraceRegs
+  if e != nil && e != err.ZeroFill {
+    return result, e
+  }
 
-  syscall.PtraceGetRegs(cmd.Process.Pid, &regs)
+  result.Bytes = hex.EncodeToString(data)
 
-  fmt.Printf("RBP: %x\n", regs.Rbp);
+  address = address + uint64(len(data))
+  length = length - uint64(len(data))
 
-  // Peek/Poke
+  if length > 0 && p.IsZeroFill(address) {
+    mapping, _ := p.GetMapping(address)
 
-  /*
-  process, err := os.StartProcess(p.Path, args, nil)
+    if end := mapping.Address + mapping.MemorySize; length > end - address {
+      length = end - address
+    }
 
-  if err != nil {
-		fmt.Println(err)
-	}
+    result.ZeroFill = length
 
-  fmt.Println("Running: [", p.Path, "], Pid: [", process.Pid, "]")
-  */
+    if section, e := p.GetSectionFromAddress(address); e == nil {
+      result.ZeroFillSection = section.Name
+    }
+  }
 
-  p.Running = true
+  return result, nil
 }
 
 func (p *Analyzer) DumpBytes(address, length uint64) {
@@ -150,22 +149,44 @@ func (p *Analyzer) DumpBytes(address, length uint64) {
     return
   }
 
-  if address > (uint64)(len(p.Data)) {
-    fmt.Printf("Address:[0x%08x] is greater than data code:[0x%08x]\n", address, len(p.Data))
+  result, e := p.GetDump(address, length)
+
+  if e != nil {
+    fmt.Printf("Address:[0x%08x] is not mapped by any segment\n", address)
 
     return
   }
 
-  if (address + length) > (uint64)(len(p.Data)) {
-    length = (uint64)(len(p.Data)) - address
+  if p.Output == "json" {
+    misc.ShowJson(result)
+
+    return
   }
 
-  fmt.Printf("%s", hex.Dump(p.Data[address:address + length]))
+  data, _ := hex.DecodeString(result.Bytes)
+
+  fmt.Printf("%s", misc.Dump(address, data))
+
+  if result.ZeroFill > 0 {
+    address = address + uint64(len(data))
+
+    name := ""
+
+    if len(result.ZeroFillSection) > 0 {
+      name = " (" + result.ZeroFillSection + ")"
+    }
+
+    fmt.Printf("0x%08x - 0x%08x: zero-fill%s, not present in file\n", address, address + result.ZeroFill, name)
+  }
 }
 
 func (p *Analyzer) GetSymbolAddress(name string) (uint64, error) {
   for _, symbol := range p.Symbols {
     if symbol.Name == name {
+      if p.File.Machine == elf.EM_ARM && elf.ST_TYPE(symbol.Info) == elf.STT_FUNC {
+        return symbol.Value &^ 1, nil // thumb bit
+      }
+
       return symbol.Value, nil
     }
   }
@@ -174,64 +195,55 @@ func (p *Analyzer) GetSymbolAddress(name string) (uint64, error) {
 }
 
 func (p *Analyzer) GetSectionAddress(name string) (uint64, error) {
-  for _, section := range p.Sections {
+  for _, section := range p.File.Sections {
     if section.Name == name {
-      return section.Offset, nil // Addr get address from memory, Offset get "address" from file
+      if len(p.Mappings) == 0 {
+        return section.Offset, nil // no segments, addresses are file offsets
+      }
+
+      if section.Flags & elf.SHF_ALLOC == 0 {
+        return 0, err.AddressNotMapped
+      }
+
+      return section.Addr, nil
     }
   }
 
   return 0, err.SectionNotFound
 }
 
-type debugInfo struct {
-  Name string
-  Description string
-}
-
 func (p *Analyzer) Process() {
   var scanner string
-  var words []string
-  var addr uint64 = p.File.Entry // INFO:: consider the memory address
-
-  term := misc.NewTerminal()
 
-  defer term.Release()
+  p.term = misc.NewTerminal()
 
-  info := &info.Information {
-    &p.State}
+  defer p.term.Release()
 
   history := []string{
     "" }
 
   historyIndex := 0
 
-  cmds := []debugInfo{
-    {"analyze", ": process sections, symbols, ..."},
-    {"symbols", ": shows symbols of binary"},
-    {"sections", ": shows sections of binary"},
-    {"seek", "<memory/symbol/section> : seek to the refered pointer address"},
-    {"dump", "[number of bytes] : show the number of bytes starting at current address"},
-    {"disassemble", "[number of instructions] : disassemble the current address"},
-    {"clear", ": clear screen"},
-    {"strings", ": show all strings in binary"},
-    {"run", ": starts the execution of process"},
-    {"quit", ": exit"}}
-
-  expr := []string{
-    "=[box] <expression> : evaluate arithmetic expression (b: binary, o: octal, h: hexadecimal)"}
-
   for true {
-    term.ClearLine()
-
-    fmt.Printf("0x%016x >> %s", addr, scanner)
+    p.term.ClearLine()
+
+    if p.IsCore() && p.Live {
+      fmt.Printf("0x%016x [core] >> %s", p.Address, scanner)
+    } else if p.Running && p.Live {
+      fmt.Printf("0x%016x [live] >> %s", p.Address, scanner)
+    } else if p.Running {
+      fmt.Printf("0x%016x [static] >> %s", p.Address, scanner)
+    } else {
+      fmt.Printf("0x%016x >> %s", p.Address, scanner)
+    }
 
-    b := term.Read()
+    b := p.term.Read()
 
     if b == '\x1b' { // escape
-      b = term.Read()
+      b = p.term.Read()
 
       if b == '\x5b' { // arrow keys
-        b = term.Read()
+        b = p.term.Read()
 
         if b == '\x41' { // up
           if historyIndex > 0 {
@@ -252,9 +264,9 @@ func (p *Analyzer) Process() {
         matches := []string{
         }
 
-        r, _ := regexp.Compile("^" + scanner)
+        r, _ := regexp.Compile("^" + regexp.QuoteMeta(scanner))
 
-        for _, cmd := range cmds {
+        for _, cmd := range commands {
           if len(r.FindString(cmd.Name)) > 0 {
             matches = append(matches[:], cmd.Name)
           }
@@ -272,137 +284,20 @@ func (p *Analyzer) Process() {
           scanner = scanner[:len(scanner) - 1]
         }
       } else if b == '\n' { // enter
-        words = strings.Fields(scanner)
-
         if len(scanner) == 0 {
           continue
         }
 
+        line := scanner
+
         history = append(history[:], "")
         historyIndex = len(history) - 1
         scanner = ""
 
         fmt.Printf("\n")
 
-        if len(words) == 0 {
-          continue
-        }
-
-        words[0] = strings.Trim(words[0], " \t")
-
-        if words[0] == "help" {
-          fmt.Println(":commands:")
-
-          for _, cmd := range cmds {
-            fmt.Println("  ", cmd.Name, cmd.Description)
-          }
-
-          fmt.Println(":expressions:")
-
-          for _, cmd := range expr {
-            fmt.Println("  ", cmd)
-          }
-        } else if words[0] == "quit" {
+        if p.Execute(line) == err.Quit {
           break
-        } else if words[0] == "clear" {
-          term.ClearScreen()
-        } else if words[0] == "analyze" {
-          p.Analyze()
-        } else if words[0] == "strings" {
-          p.ShowStrings()
-        } else if words[0] == "run" {
-          p.RunProcess([]string{""})
-        } else if words[0] == "info" {
-          info.ShowInformation()
-        } else if words[0] == "dump" {
-          var n uint64 = 32
-
-          if len(words) > 1 {
-            i, err := strconv.ParseUint(words[1], 10, 64)
-
-            if err == nil {
-              n = i
-            }
-          }
-
-          p.DumpBytes(addr, n)
-        } else if words[0] == "symbols" {
-          info.ShowSymbols()
-        } else if words[0] == "sections" {
-          info.ShowSections()
-        } else if words[0] == "seek" {
-          if len(words) == 2 {
-            i, err := p.GetSymbolAddress(words[1])
-
-            if err != nil {
-              i, err := p.GetSectionAddress(words[1])
-
-              if err != nil {
-                i, err := strconv.ParseUint(words[1], 10, 64)
-
-                if err != nil {
-                  if strings.HasPrefix(words[1], "0x") {
-                    i, err := strconv.ParseUint(words[1][2:], 16, 64)
-
-                    if err != nil {
-                      fmt.Println("address not found")
-                    } else {
-                      addr = i
-                    }
-                  } else {
-                    fmt.Println("address not found")
-                  }
-                } else {
-                  addr = i
-                }
-              } else {
-                addr = i
-              }
-            } else {
-              addr = i
-            }
-          }
-
-          // fmt.Printf("seek: 0x%08x\n", addr)
-        } else if words[0] == "disassemble" {
-          n := 32
-
-          if len(words) > 1 {
-            i, err := strconv.Atoi(words[1])
-
-            if err == nil {
-              n = i
-            }
-
-            if n < 0 {
-              n = i
-            }
-          }
-
-          err := info.ShowAssemble(addr, n)
-
-          if err != nil {
-            fmt.Println("Invalid operators")
-          }
-        } else if strings.HasPrefix(words[0], "=") {
-          cmd := strings.ToLower(words[0])
-          expr := strings.Join(words[1:], "")
-
-          if r, err := misc.ParseAndEval(expr); err == nil {
-            if strings.HasPrefix(cmd, "=x") {
-              fmt.Printf("0x%s\n", strconv.FormatUint(uint64(r), 16))
-            } else if strings.HasPrefix(cmd, "=o") {
-              fmt.Printf("0%s\n", strconv.FormatUint(uint64(r), 8))
-            } else if strings.HasPrefix(cmd, "=b") {
-              fmt.Printf("0b%s\n", strconv.FormatUint(uint64(r), 2))
-            } else {
-              fmt.Println(r)
-            }
-          } else {
-            fmt.Println("invalid expression: ", err)
-          }
-        } else {
-          fmt.Println("command not found")
         }
       } else {
         if b >= 0x20 && b < 0x7f {
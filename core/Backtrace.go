@@ -0,0 +1,141 @@
+package core
+
+import (
+  "debug/dwarf"
+  "fmt"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type FrameInfo struct {
+  Level int `json:"level"`
+  Address uint64 `json:"address"`
+  Symbol string `json:"symbol,omitempty"`
+  Offset uint64 `json:"offset"`
+  Library string `json:"library,omitempty"`
+  File string `json:"file,omitempty"`
+  Line int `json:"line,omitempty"`
+}
+
+// the frames of the current thread, unwound with the call frame information
+// of the files or with the frame pointers
+func (p *Analyzer) getFrames() ([]debug.Frame, error) {
+  if p.IsCore() {
+    return p.core.Backtrace()
+  }
+
+  return p.debugger.Backtrace()
+}
+
+func (p *Analyzer) GetBacktrace() ([]FrameInfo, error) {
+  frames, e := p.getFrames()
+
+  if e != nil {
+    return nil, e
+  }
+
+  libraries, bias := p.getLibraries()
+
+  var result []FrameInfo
+
+  for i, frame := range frames {
+    info := FrameInfo{
+      Level: i, Address: frame.Pc}
+
+    if name, offset, e := p.GetProcessSymbol(frame.Pc); e == nil {
+      info.Symbol, info.Offset = name, offset
+    }
+
+    if library := libraries.GetLibraryAt(frame.Pc); library != nil {
+      info.Library = library.Name
+    } else {
+      // the return addresses are after the call, which may end the line
+      addr := frame.Pc - bias
+
+      if i > 0 {
+        addr = addr - 1
+      }
+
+      info.File, info.Line = p.getFrameLine(addr)
+    }
+
+    result = append(result, info)
+  }
+
+  return result, nil
+}
+
+// the line of a frame, only the line table of its compilation unit is read
+func (p *Analyzer) getFrameLine(addr uint64) (string, int) {
+  data, e := p.getDwarf()
+
+  if e != nil {
+    return "", 0
+  }
+
+  unit, e := data.Reader().SeekPC(addr)
+
+  if e != nil {
+    return "", 0
+  }
+
+  lines, e := data.LineReader(unit)
+
+  if e != nil || lines == nil {
+    return "", 0
+  }
+
+  var line dwarf.LineEntry
+
+  if lines.SeekPC(addr, &line) != nil || line.File == nil {
+    return "", 0
+  }
+
+  return line.File.Name, line.Line
+}
+
+func (p *Analyzer) ShowBacktrace() error {
+  if p.debugger.Running == false && p.IsCore() == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  frames, e := p.GetBacktrace()
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(frames)
+
+    return nil
+  }
+
+  for _, frame := range frames {
+    symbol := "??"
+
+    if len(frame.Symbol) > 0 && frame.Offset == 0 {
+      symbol = frame.Symbol
+    } else if len(frame.Symbol) > 0 {
+      symbol = fmt.Sprintf("%s+%d", frame.Symbol, frame.Offset)
+    }
+
+    fmt.Printf("#%-3d 0x%016x in %s", frame.Level, frame.Address, symbol)
+
+    if len(frame.File) > 0 {
+      fmt.Printf(" at %s:%d", frame.File, frame.Line)
+    } else if len(frame.Library) > 0 {
+      fmt.Printf(" from %s", frame.Library)
+    }
+
+    fmt.Println()
+  }
+
+  return nil
+}
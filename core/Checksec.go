@@ -0,0 +1,304 @@
+package core
+
+import (
+  "debug/elf"
+  "fmt"
+  "sort"
+  "strings"
+
+  "jelf/core/misc"
+)
+
+const (
+  ntGnuPropertyType0 = 5
+  gnuPropertyX86Feature1And = 0xc0000002
+  gnuPropertyAarch64Feature1And = 0xc0000000
+)
+
+type Hardening struct {
+  Relro string `json:"relro"`
+  NX bool `json:"nx"`
+  PIE string `json:"pie"`
+  Canary string `json:"canary"`
+  Fortify []string `json:"fortify"`
+  Static bool `json:"static"`
+  Rpath string `json:"rpath"`
+  Runpath string `json:"runpath"`
+  Features []string `json:"features"`
+  Failures []string `json:"failures,omitempty"`
+}
+
+func (p *Analyzer) GetImports() []elf.Symbol {
+  var imports []elf.Symbol
+
+  for _, symbol := range p.DynamicSymbols {
+    if symbol.Section == elf.SHN_UNDEF && len(symbol.Name) > 0 {
+      imports = append(imports, symbol)
+    }
+  }
+
+  return imports
+}
+
+func (p *Analyzer) GetPropertyFeatures() []string {
+  features := []string{}
+
+  align := uint64(8)
+
+  if p.File.Class == elf.ELFCLASS32 {
+    align = 4
+  }
+
+  for _, note := range p.GetNotes() {
+    if note.Name != "GNU" || note.Type != ntGnuPropertyType0 {
+      continue
+    }
+
+    data := note.Desc
+
+    for len(data) >= 8 {
+      t := p.File.ByteOrder.Uint32(data)
+      size := uint64(p.File.ByteOrder.Uint32(data[4:]))
+
+      if 8 + size > uint64(len(data)) {
+        break
+      }
+
+      if size >= 4 {
+        bits := p.File.ByteOrder.Uint32(data[8:])
+
+        if t == gnuPropertyX86Feature1And {
+          if bits & 0x1 != 0 {
+            features = append(features, "IBT")
+          }
+
+          if bits & 0x2 != 0 {
+            features = append(features, "SHSTK")
+          }
+        } else if t == gnuPropertyAarch64Feature1And {
+          if bits & 0x1 != 0 {
+            features = append(features, "BTI")
+          }
+
+          if bits & 0x2 != 0 {
+            features = append(features, "PAC")
+          }
+        }
+      }
+
+      next := (8 + size + align - 1) &^ (align - 1)
+
+      if next > uint64(len(data)) {
+        break
+      }
+
+      data = data[next:]
+    }
+  }
+
+  return features
+}
+
+func (p *Analyzer) Checksec() Hardening {
+  result := Hardening{
+    Relro: "none", PIE: "none", Canary: "none", Fortify: []string{}, Features: []string{}}
+
+  bindNow := false
+
+  if _, e := p.GetDynamic(elf.DT_BIND_NOW); e == nil {
+    bindNow = true
+  }
+
+  if entry, e := p.GetDynamic(elf.DT_FLAGS); e == nil && entry.Value & uint64(elf.DF_BIND_NOW) != 0 {
+    bindNow = true
+  }
+
+  if entry, e := p.GetDynamic(elf.DT_FLAGS_1); e == nil && entry.Value & 0x1 != 0 { // DF_1_NOW
+    bindNow = true
+  }
+
+  interp := false
+  stack := false
+
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_GNU_RELRO {
+      result.Relro = "partial"
+    } else if prog.Type == elf.PT_GNU_STACK {
+      stack = true
+      result.NX = prog.Flags & elf.PF_X == 0
+    } else if prog.Type == elf.PT_INTERP {
+      interp = true
+    }
+  }
+
+  // without PT_GNU_STACK the kernel maps an executable stack
+  if stack == false {
+    result.NX = false
+  }
+
+  if result.Relro == "partial" && bindNow {
+    result.Relro = "full"
+  }
+
+  if p.File.Type == elf.ET_DYN {
+    if entry, e := p.GetDynamic(elf.DT_FLAGS_1); interp || (e == nil && entry.Value & 0x08000000 != 0) { // DF_1_PIE
+      result.PIE = "pie"
+    } else {
+      result.PIE = "dso"
+    }
+  }
+
+  // static binaries carry the libc functions themselves, which are defined
+  // and called by libc whether the program uses them or not
+  if len(p.DynamicSymbols) == 0 {
+    result.Static = true
+    result.Canary = "unknown"
+  }
+
+  for _, symbol := range p.GetImports() {
+    if symbol.Name == "__stack_chk_fail" || symbol.Name == "__stack_chk_guard" {
+      result.Canary = "found"
+    } else if strings.HasPrefix(symbol.Name, "__") && strings.HasSuffix(symbol.Name, "_chk") {
+      result.Fortify = append(result.Fortify, symbol.Name)
+    }
+  }
+
+  sort.Strings(result.Fortify)
+
+  for _, entry := range p.Dynamic {
+    if entry.Tag == elf.DT_RPATH {
+      result.Rpath = strings.TrimSuffix(strings.TrimPrefix(entry.Text, "Library rpath: ["), "]")
+    } else if entry.Tag == elf.DT_RUNPATH {
+      result.Runpath = strings.TrimSuffix(strings.TrimPrefix(entry.Text, "Library runpath: ["), "]")
+    }
+  }
+
+  result.Features = p.GetPropertyFeatures()
+
+  return result
+}
+
+func (p *Analyzer) HasFeature(hardening Hardening, feature string) bool {
+  for _, f := range hardening.Features {
+    if strings.EqualFold(f, feature) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// checks the hardening against a list of requirements and returns the ones not met
+func (p *Analyzer) CheckPolicy(hardening Hardening, requirements []string) []string {
+  var failures []string
+
+  for _, requirement := range requirements {
+    ok := true
+
+    switch strings.ToLower(requirement) {
+      case "relro":
+        ok = hardening.Relro != "none"
+      case "full-relro":
+        ok = hardening.Relro == "full"
+      case "nx":
+        ok = hardening.NX
+      case "pie":
+        ok = hardening.PIE == "pie"
+      case "canary":
+        ok = hardening.Canary == "found"
+      case "fortify":
+        ok = len(hardening.Fortify) > 0
+      case "no-rpath":
+        ok = len(hardening.Rpath) == 0 && len(hardening.Runpath) == 0
+      case "ibt", "shstk", "bti", "pac":
+        ok = p.HasFeature(hardening, requirement)
+      default:
+        failures = append(failures, requirement + " (unknown requirement)")
+
+        continue
+    }
+
+    if ok == false {
+      failures = append(failures, requirement)
+    }
+  }
+
+  return failures
+}
+
+// returns false when any of the requirements is not met
+func (p *Analyzer) ShowChecksec(requirements []string) bool {
+  if p.Analyzed == false {
+    fmt.Println("Call 'analyze' before")
+
+    return false
+  }
+
+  hardening := p.Checksec()
+
+  if p.Output == "json" {
+    hardening.Failures = p.CheckPolicy(hardening, requirements)
+
+    misc.ShowJson(hardening)
+
+    return len(hardening.Failures) == 0
+  }
+
+  relro := map[string]string{
+    "none": "No RELRO", "partial": "Partial RELRO", "full": "Full RELRO"}
+
+  fmt.Printf("%-10s %s\n", "RELRO:", relro[hardening.Relro])
+
+  if hardening.Canary == "found" {
+    fmt.Printf("%-10s %s\n", "Stack:", "Canary found")
+  } else if hardening.Canary == "unknown" {
+    fmt.Printf("%-10s %s\n", "Stack:", "Unknown (static binary)")
+  } else {
+    fmt.Printf("%-10s %s\n", "Stack:", "No canary found")
+  }
+
+  if hardening.NX {
+    fmt.Printf("%-10s %s\n", "NX:", "NX enabled")
+  } else {
+    fmt.Printf("%-10s %s\n", "NX:", "NX disabled")
+  }
+
+  pie := map[string]string{
+    "none": "No PIE", "pie": "PIE enabled", "dso": "DSO"}
+
+  fmt.Printf("%-10s %s\n", "PIE:", pie[hardening.PIE])
+
+  if len(hardening.Fortify) > 0 {
+    fmt.Printf("%-10s %s (%s)\n", "FORTIFY:", "Enabled", strings.Join(hardening.Fortify, ", "))
+  } else if hardening.Static {
+    fmt.Printf("%-10s %s\n", "FORTIFY:", "Unknown (static binary)")
+  } else {
+    fmt.Printf("%-10s %s\n", "FORTIFY:", "No fortified functions")
+  }
+
+  if len(hardening.Rpath) > 0 {
+    fmt.Printf("%-10s %s\n", "RPATH:", hardening.Rpath)
+  } else {
+    fmt.Printf("%-10s %s\n", "RPATH:", "No RPATH")
+  }
+
+  if len(hardening.Runpath) > 0 {
+    fmt.Printf("%-10s %s\n", "RUNPATH:", hardening.Runpath)
+  } else {
+    fmt.Printf("%-10s %s\n", "RUNPATH:", "No RUNPATH")
+  }
+
+  if len(hardening.Features) > 0 {
+    fmt.Printf("%-10s %s\n", "CET:", strings.Join(hardening.Features, " "))
+  } else {
+    fmt.Printf("%-10s %s\n", "CET:", "No CET features")
+  }
+
+  failures := p.CheckPolicy(hardening, requirements)
+
+  for _, failure := range failures {
+    fmt.Println("policy failed:", failure)
+  }
+
+  return len(failures) == 0
+}
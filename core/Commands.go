@@ -0,0 +1,410 @@
+package core
+
+import (
+  "bufio"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "jelf/core/err"
+  "jelf/core/info"
+  "jelf/core/misc"
+)
+
+type debugInfo struct {
+  Name string
+  Description string
+}
+
+var commands = []debugInfo{
+  {"analyze", ": process sections, symbols, ..."},
+  {"info", "[line <address/file:line>] : shows the elf header of binary, or the source line of an address and the range of its code"},
+  {"symbols", ": shows symbols of binary"},
+  {"sections", ": shows sections of binary"},
+  {"segments", ": shows program headers (segments) and the sections mapped by each one"},
+  {"dynamic", ": shows the entries of the dynamic section"},
+  {"relocs", ": shows the decoded relocation tables"},
+  {"checksec", "[relro|full-relro|nx|pie|canary|fortify|no-rpath|ibt|shstk|bti|pac ...] : shows the hardening of binary, checking the requirements"},
+  {"seek", "<memory/symbol/section/file:line> : seek to the refered pointer address"},
+  {"dump", "[number of bytes] : show the number of bytes starting at current address"},
+  {"write", "<memory/symbol/register> <hex bytes>|=<expression> : writes the bytes (ex: 90 90 c3) or the 64 bits value of the expression in the process"},
+  {"disassemble", "[number of instructions] [16|32|64|arm|thumb|arm64] : disassemble the current address (optionally forcing the decoder mode)"},
+  {"set", "<option> <value> : changes a session option (output text|json, view static|live, env KEY=VALUE, cwd <dir>, tty <device|pty|inherit>, disable-randomization on|off, follow-fork parent|child), or a register with 'set $reg = expression'"},
+  {"unset", "env [KEY] : removes a variable of the environment of the process, or all of them"},
+  {"regs", "[general|fp|sse|avx|all] : shows the registers of the process"},
+  {"clear", ": clear screen"},
+  {"strings", ": show all strings in binary"},
+  {"run", "[args] [< input] [> output] [>> output] [2> errors] : starts the execution of process under the debugger"},
+  {"kill", ": kills the running process"},
+  {"attach", "<pid> : loads the executable of the process and traces it"},
+  {"detach", ": stops tracing the attached process, removing the breakpoints"},
+  {"maps", ": shows the memory maps of the process"},
+  {"core", ": shows the process of the core file, the signal which killed it and its threads"},
+  {"gcore", "[file] : writes the process to a core file, core.<pid> by default"},
+  {"sharedlibs", ": shows the shared libraries loaded in the process, with their base, range and number of symbols"},
+  {"threads", ": shows the threads of the process, the current one is marked by *"},
+  {"thread", "<tid> : switches to the thread, the one of the registers and the steps"},
+  {"bt", ": shows the frames of the current thread, with their symbols and their source lines"},
+  {"types", ": shows the types of the dwarf information, with their sizes"},
+  {"ptype", "<name> : shows the layout of a type, the signature of a function or the type of a variable"},
+  {"locals", ": shows the parameters and variables of the current function, with their values in a process or a core"},
+  {"break", "[symbol/address] : sets a breakpoint, or lists them without arguments"},
+  {"watch", "[symbol/address] [length] [r|w|rw] : stops when the memory is accessed (up to 4 watchpoints), or lists them without arguments"},
+  {"delete", "[ids] : deletes the breakpoints and watchpoints, or all of them without arguments"},
+  {"continue", ": continues the process until a breakpoint, a signal or the exit"},
+  {"stepi", "[count] : executes the next instructions"},
+  {"nexti", "[count] : executes the next instructions, stepping over calls"},
+  {"finish", ": runs until the current function returns"},
+  {"trace", "syscalls [name,...] [stop name,...] : continues showing the syscalls with the decoded arguments, stopping at the entry of the ones of the stop list"},
+  {"quit", ": exit"}}
+
+var expressions = []string{
+  "=[box] <expression> : evaluate arithmetic expression (b: binary, o: octal, h: hexadecimal), $reg is the value of a register"}
+
+func IsCommand(name string) bool {
+  for _, cmd := range commands {
+    if cmd.Name == name {
+      return true
+    }
+  }
+
+  return false
+}
+
+func (p *Analyzer) ResolveAddress(text string) (uint64, error) {
+  if strings.HasPrefix(text, "$") {
+    return p.EvaluateExpression(text)
+  }
+
+  if i, e := p.GetSymbolAddress(text); e == nil {
+    return p.ViewAddress(i), nil
+  }
+
+  if i, e := p.GetSectionAddress(text); e == nil {
+    return p.ViewAddress(i), nil
+  }
+
+  // the libraries are only in the memory of the process
+  if libraries, _ := p.getLibraries(); p.Live {
+    if i, e := libraries.GetLibrarySymbolAddress(text); e == nil {
+      return i, nil
+    }
+  }
+
+  // file.c:42, with the dwarf line tables
+  if i := strings.LastIndex(text, ":"); i > 0 {
+    if line, e := strconv.Atoi(text[i + 1:]); e == nil {
+      if addr, e := p.GetLineAddress(text[:i], line); e == nil {
+        return p.ViewAddress(addr), nil
+      }
+    }
+  }
+
+  if i, e := strconv.ParseUint(text, 10, 64); e == nil {
+    return i, nil
+  }
+
+  if strings.HasPrefix(text, "0x") {
+    if i, e := strconv.ParseUint(text[2:], 16, 64); e == nil {
+      return i, nil
+    }
+  }
+
+  return 0, err.AddressNotFound
+}
+
+func (p *Analyzer) SetOption(args []string) error {
+  if len(args) > 0 && strings.HasPrefix(args[0], "$") {
+    return p.SetRegister(args)
+  }
+
+  if len(args) > 0 && (args[0] == "env" || args[0] == "cwd" || args[0] == "tty" || args[0] == "disable-randomization" || args[0] == "follow-fork") {
+    return p.SetProcessOption(args)
+  }
+
+  if len(args) == 2 && args[0] == "output" && (args[1] == "text" || args[1] == "json") {
+    p.Output = args[1]
+
+    return nil
+  }
+
+  if len(args) == 2 && args[0] == "view" && (args[1] == "static" || args[1] == "live") {
+    if e := p.SetLive(args[1] == "live"); e != nil {
+      fmt.Println("process is not running")
+
+      return e
+    }
+
+    return nil
+  }
+
+  fmt.Println("invalid option")
+
+  return err.InvalidOption
+}
+
+// executes a single command line, returning err.Quit when the session must end
+func (p *Analyzer) Execute(line string) error {
+  words := strings.Fields(line)
+
+  if len(words) == 0 {
+    return nil
+  }
+
+  info := &info.Information {
+    State: &p.State}
+
+  if words[0] == "help" {
+    fmt.Println(":commands:")
+
+    for _, cmd := range commands {
+      fmt.Println("  ", cmd.Name, cmd.Description)
+    }
+
+    fmt.Println(":expressions:")
+
+    for _, cmd := range expressions {
+      fmt.Println("  ", cmd)
+    }
+  } else if words[0] == "quit" {
+    if p.debugger.Attached {
+      p.DetachProcess()
+    }
+
+    return err.Quit
+  } else if words[0] == "clear" {
+    if p.term != nil {
+      p.term.ClearScreen()
+    }
+  } else if words[0] == "analyze" {
+    p.Analyze()
+  } else if words[0] == "strings" {
+    p.ShowStrings()
+  } else if words[0] == "run" {
+    return p.RunProcess(words[1:])
+  } else if words[0] == "kill" {
+    return p.KillProcess()
+  } else if words[0] == "attach" {
+    if len(words) != 2 {
+      fmt.Println("invalid arguments")
+
+      return err.InvalidOption
+    }
+
+    pid, e := strconv.Atoi(words[1])
+
+    if e != nil {
+      fmt.Println("invalid pid:", words[1])
+
+      return err.InvalidOption
+    }
+
+    return p.AttachProcess(pid)
+  } else if words[0] == "detach" {
+    return p.DetachProcess()
+  } else if words[0] == "maps" {
+    return p.ShowMaps()
+  } else if words[0] == "core" {
+    return p.ShowCore()
+  } else if words[0] == "gcore" {
+    return p.GenerateCore(words[1:])
+  } else if words[0] == "sharedlibs" {
+    return p.ShowLibraries()
+  } else if words[0] == "threads" {
+    return p.ShowThreads()
+  } else if words[0] == "thread" {
+    if len(words) != 2 {
+      fmt.Println("invalid arguments")
+
+      return err.InvalidOption
+    }
+
+    return p.SwitchThread(words[1])
+  } else if words[0] == "bt" {
+    return p.ShowBacktrace()
+  } else if words[0] == "types" {
+    return p.ShowTypes()
+  } else if words[0] == "ptype" {
+    if len(words) == 1 {
+      fmt.Println("invalid arguments")
+
+      return err.InvalidOption
+    }
+
+    return p.ShowTypeDetails(strings.Join(words[1:], " "))
+  } else if words[0] == "locals" {
+    return p.ShowLocals()
+  } else if words[0] == "trace" {
+    return p.TraceSyscalls(words[1:])
+  } else if words[0] == "watch" {
+    if len(words) == 1 {
+      p.ShowWatchpoints()
+    } else {
+      return p.AddWatchpoint(words[1:])
+    }
+  } else if words[0] == "break" {
+    if len(words) == 1 {
+      p.ShowBreakpoints()
+    } else {
+      return p.AddBreakpoint(words[1])
+    }
+  } else if words[0] == "regs" {
+    kind := ""
+
+    if len(words) > 1 {
+      kind = words[1]
+    }
+
+    return p.ShowRegisters(kind)
+  } else if words[0] == "delete" {
+    return p.DeleteBreakpoints(words[1:])
+  } else if words[0] == "continue" || words[0] == "stepi" || words[0] == "nexti" || words[0] == "finish" {
+    n := 1
+
+    if len(words) > 1 {
+      i, err := strconv.Atoi(words[1])
+
+      if err == nil && i > 0 {
+        n = i
+      }
+    }
+
+    return p.StepProcess(words[0], n)
+  } else if words[0] == "info" && len(words) == 3 && words[1] == "line" {
+    return p.ShowLineInfo(words[2])
+  } else if words[0] == "info" {
+    info.ShowInformation()
+  } else if words[0] == "dump" {
+    var n uint64 = 32
+
+    if len(words) > 1 {
+      i, err := strconv.ParseUint(words[1], 10, 64)
+
+      if err == nil {
+        n = i
+      }
+    }
+
+    p.DumpBytes(p.Address, n)
+  } else if words[0] == "write" {
+    return p.WriteProcess(words[1:])
+  } else if words[0] == "symbols" {
+    info.ShowSymbols()
+  } else if words[0] == "sections" {
+    info.ShowSections()
+  } else if words[0] == "segments" {
+    info.ShowSegments()
+  } else if words[0] == "dynamic" {
+    info.ShowDynamic()
+  } else if words[0] == "relocs" {
+    info.ShowRelocations()
+  } else if words[0] == "checksec" {
+    if p.ShowChecksec(words[1:]) == false {
+      return err.PolicyFailed
+    }
+  } else if words[0] == "set" {
+    return p.SetOption(words[1:])
+  } else if words[0] == "unset" {
+    return p.UnsetOption(words[1:])
+  } else if words[0] == "seek" {
+    if len(words) == 2 {
+      i, e := p.ResolveAddress(words[1])
+
+      if e != nil {
+        fmt.Println("address not found")
+
+        return e
+      }
+
+      p.Address = p.ViewAddress(p.ToFileAddress(i))
+    }
+  } else if words[0] == "disassemble" {
+    n := 32
+    mode := ""
+
+    if len(words) > 1 {
+      i, err := strconv.Atoi(words[1])
+
+      if err == nil {
+        n = i
+      }
+
+      if n < 0 {
+        n = i
+      }
+    }
+
+    if len(words) > 2 {
+      mode = words[2]
+    }
+
+    e := info.ShowAssemble(p.Address, n, mode)
+
+    if e != nil {
+      fmt.Println(e)
+
+      return e
+    }
+  } else if strings.HasPrefix(words[0], "=") {
+    cmd := strings.ToLower(words[0])
+    expr, e := p.ExpandRegisters(strings.Join(words[1:], ""))
+
+    if e != nil {
+      fmt.Println("invalid register: ", e)
+
+      return e
+    }
+
+    r, e := misc.ParseAndEval(expr)
+
+    if e != nil {
+      fmt.Println("invalid expression: ", e)
+
+      return e
+    }
+
+    if strings.HasPrefix(cmd, "=x") {
+      fmt.Printf("0x%s\n", strconv.FormatUint(uint64(r), 16))
+    } else if strings.HasPrefix(cmd, "=o") {
+      fmt.Printf("0%s\n", strconv.FormatUint(uint64(r), 8))
+    } else if strings.HasPrefix(cmd, "=b") {
+      fmt.Printf("0b%s\n", strconv.FormatUint(uint64(r), 2))
+    } else {
+      fmt.Println(r)
+    }
+  } else {
+    fmt.Println("command not found")
+
+    return err.CommandNotFound
+  }
+
+  return nil
+}
+
+// executes the commands separated by ';' or new lines, ignoring comments
+// started by '#'. Stops at 'quit' and returns the last failure, if any
+func (p *Analyzer) ExecuteScript(script string) error {
+  var failure error
+
+  scanner := bufio.NewScanner(strings.NewReader(script))
+
+  for scanner.Scan() {
+    line := scanner.Text()
+
+    if i := strings.Index(line, "#"); i >= 0 {
+      line = line[:i]
+    }
+
+    for _, cmd := range strings.Split(line, ";") {
+      e := p.Execute(cmd)
+
+      if e == err.Quit {
+        return failure
+      } else if e != nil {
+        failure = e
+      }
+    }
+  }
+
+  return failure
+}
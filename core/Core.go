@@ -0,0 +1,239 @@
+package core
+
+import (
+  "debug/elf"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strconv"
+  "syscall"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/misc"
+  "jelf/core/state"
+)
+
+type CoreInfo struct {
+  Pid int `json:"pid"`
+  Name string `json:"name"`
+  Args string `json:"args"`
+  Executable string `json:"executable,omitempty"`
+  Bias uint64 `json:"bias"`
+  Signal string `json:"signal,omitempty"`
+  Code int32 `json:"code"`
+  Fault uint64 `json:"fault,omitempty"`
+  Threads []ThreadInfo `json:"threads"`
+  Files []debug.MappedFile `json:"files"`
+}
+
+// the shared libraries of the running process or of the core
+type libraryList interface {
+  GetLibraryAt(addr uint64) *debug.Library
+  GetLibrarySymbol(addr uint64) (string, uint64, error)
+  GetLibrarySymbolAddress(name string) (uint64, error)
+}
+
+// opens the executable of the core, found at its path or next to the core, as
+// the file of the session, with the memory of the core as the live view. Without
+// it the core itself is analyzed
+func NewCoreAnalyzer(path string, file *elf.File) (*Analyzer, error) {
+  core, e := debug.OpenCore(path, file)
+
+  if e != nil {
+    return nil, e
+  }
+
+  analyzer := &Analyzer{
+    State: state.State{Path: path, File: file}, debugger: debug.NewDebugger(path, file), core: core}
+
+  analyzer.LoadMappings()
+
+  exe := core.GetExecutable()
+
+  if len(exe) == 0 {
+    return analyzer, nil
+  }
+
+  if _, e := os.Stat(exe); e != nil {
+    exe = filepath.Join(filepath.Dir(path), filepath.Base(exe))
+  }
+
+  executable, e := elf.Open(exe)
+
+  if e != nil {
+    core.SetExecutable(exe, nil)
+
+    return analyzer, nil
+  }
+
+  core.SetExecutable(exe, executable)
+
+  analyzer.State = state.State{
+    Path: exe, File: executable}
+
+  analyzer.debugger = debug.NewDebugger(exe, executable)
+
+  analyzer.LoadMappings()
+  analyzer.SetLive(true)
+
+  analyzer.Address = executable.Entry + core.Bias
+
+  if pc, e := core.GetRegister("rip"); e == nil {
+    analyzer.Address = pc
+  }
+
+  return analyzer, nil
+}
+
+// the session shows a core, unless a process was started since
+func (p *Analyzer) IsCore() bool {
+  return p.core != nil && p.debugger.Running == false
+}
+
+func (p *Analyzer) getLibraries() (libraryList, uint64) {
+  if p.IsCore() {
+    return p.core, p.core.Bias
+  }
+
+  return p.debugger, p.debugger.Bias
+}
+
+func (p *Analyzer) getMemory() state.Memory {
+  if p.IsCore() {
+    return p.core
+  }
+
+  return p.debugger
+}
+
+func (p *Analyzer) GetRegisters() (syscall.PtraceRegs, error) {
+  if p.IsCore() {
+    return p.core.GetRegisters()
+  }
+
+  return p.debugger.GetRegisters()
+}
+
+func (p *Analyzer) GetRegister(name string) (uint64, error) {
+  if p.IsCore() {
+    return p.core.GetRegister(name)
+  }
+
+  return p.debugger.GetRegister(name)
+}
+
+func (p *Analyzer) GetFPRegisters() (debug.FPRegisters, error) {
+  if p.IsCore() {
+    return p.core.GetFPRegisters()
+  }
+
+  return p.debugger.GetFPRegisters()
+}
+
+func (p *Analyzer) GetCoreInfo() CoreInfo {
+  core := p.core
+
+  result := CoreInfo{
+    Pid: core.Pid, Name: core.Name, Args: core.Args, Code: core.Code, Fault: core.Fault, Files: core.Files}
+
+  if p.File != core.File {
+    result.Executable = p.Path
+    result.Bias = core.Bias
+  }
+
+  if core.Signal != 0 {
+    result.Signal = core.Signal.String()
+  }
+
+  result.Threads = p.GetCoreThreads()
+
+  return result
+}
+
+func (p *Analyzer) GetCoreThreads() []ThreadInfo {
+  var threads []ThreadInfo
+
+  for _, thread := range p.core.Threads {
+    info := ThreadInfo{
+      Tid: thread.Tid, Name: p.core.Name, Address: thread.Registers.Rip, Symbol: p.GetProcessLocation(thread.Registers.Rip), Current: thread.Tid == p.core.Tid}
+
+    threads = append(threads, info)
+  }
+
+  return threads
+}
+
+// shows the process of the core, the signal which killed it and its threads
+func (p *Analyzer) ShowCore() error {
+  if p.core == nil {
+    fmt.Println("not a core file")
+
+    return err.InvalidOption
+  }
+
+  result := p.GetCoreInfo()
+
+  if p.Output == "json" {
+    misc.ShowJson(result)
+
+    return nil
+  }
+
+  fmt.Printf("Core of process %d (%s): %s\n", result.Pid, result.Name, result.Args)
+
+  if len(result.Executable) > 0 {
+    fmt.Printf("Executable %s, load base 0x%x\n", result.Executable, result.Bias)
+  } else {
+    fmt.Println("Executable not found, the core is analyzed alone")
+  }
+
+  if len(result.Signal) > 0 {
+    fmt.Printf("Program terminated with signal %s, code %d", result.Signal, result.Code)
+
+    if p.core.HasFault() {
+      fmt.Printf(", address 0x%x", result.Fault)
+    }
+
+    fmt.Println()
+  }
+
+  for _, thread := range result.Threads {
+    mark := " "
+
+    if thread.Current {
+      mark = "*"
+    }
+
+    fmt.Printf("%s %-8d 0x%016x%s\n", mark, thread.Tid, thread.Address, thread.Symbol)
+  }
+
+  return nil
+}
+
+// gcore [file], the default name is core.<pid>
+func (p *Analyzer) GenerateCore(args []string) error {
+  if p.debugger.Running == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  path := "core." + strconv.Itoa(p.debugger.Pid)
+
+  if len(args) > 0 {
+    path = args[0]
+  }
+
+  if e := p.debugger.WriteCore(path); e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output != "json" {
+    fmt.Printf("Saved corefile %s\n", path)
+  }
+
+  return nil
+}
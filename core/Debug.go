@@ -0,0 +1,801 @@
+package core
+
+import (
+  "debug/elf"
+  "encoding/hex"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "strconv"
+  "strings"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/info"
+  "jelf/core/misc"
+  "jelf/core/state"
+)
+
+type Stop struct {
+  Pid int `json:"pid"`
+  Tid int `json:"tid"`
+  Running bool `json:"running"`
+  ExitCode int `json:"exit_code"`
+  Signal string `json:"signal,omitempty"`
+  Breakpoint int `json:"breakpoint,omitempty"`
+  Watchpoint *debug.Watchpoint `json:"watchpoint,omitempty"`
+  Instruction uint64 `json:"instruction,omitempty"` // the one which triggered the watchpoint
+  Address uint64 `json:"address"`
+  Symbol string `json:"symbol,omitempty"`
+  Library string `json:"library,omitempty"`
+}
+
+// returns ' <symbol+offset>' for the address of the file, if any
+func (p *Analyzer) GetLocation(addr uint64) string {
+  info := &info.Information {
+    State: &p.State}
+
+  name, offset, e := info.GetNearestSymbol(addr)
+
+  if e != nil {
+    return ""
+  }
+
+  if offset == 0 {
+    return " <" + name + ">"
+  }
+
+  return fmt.Sprintf(" <%s+%d>", name, offset)
+}
+
+// finds the symbol of the address of the process, in the executable or in a shared library
+func (p *Analyzer) GetProcessSymbol(addr uint64) (string, uint64, error) {
+  libraries, bias := p.getLibraries()
+
+  if libraries.GetLibraryAt(addr) != nil {
+    return libraries.GetLibrarySymbol(addr)
+  }
+
+  info := &info.Information {
+    State: &p.State}
+
+  return info.GetNearestSymbol(addr - bias)
+}
+
+// returns ' <symbol+offset>' for the address of the process
+func (p *Analyzer) GetProcessLocation(addr uint64) string {
+  name, offset, e := p.GetProcessSymbol(addr)
+
+  if e != nil {
+    return ""
+  }
+
+  if offset == 0 {
+    return " <" + name + ">"
+  }
+
+  return fmt.Sprintf(" <%s+%d>", name, offset)
+}
+
+// converts an address of the current view to the one of the file. Addresses
+// of the process are accepted by the static view too
+func (p *Analyzer) ToFileAddress(addr uint64) uint64 {
+  if p.Live {
+    return p.FileAddress(addr)
+  }
+
+  if bias := p.debugger.Bias; p.debugger.Running && bias != 0 && addr >= bias {
+    if _, e := p.GetMapping(addr); e != nil {
+      return addr - bias
+    }
+  }
+
+  return addr
+}
+
+// switches between the memory of the process and the one of the file, keeping the current address
+func (p *Analyzer) SetLive(live bool) error {
+  if live && p.debugger.Running == false && p.core == nil {
+    return err.NotRunning
+  }
+
+  addr := p.ToFileAddress(p.Address)
+
+  if p.IsCore() {
+    p.Memory = p.core
+    p.Libraries = p.core
+    p.Bias = p.core.Bias
+  } else {
+    p.Memory = p.debugger
+    p.Libraries = p.debugger
+    p.Bias = p.debugger.Bias
+  }
+
+  p.Live = live
+
+  p.Address = p.ViewAddress(addr)
+
+  return nil
+}
+
+func (p *Analyzer) GetStop() Stop {
+  debugger := p.debugger
+
+  result := Stop{
+    Pid: debugger.Pid, Tid: debugger.Tid, Running: debugger.Running}
+
+  if debugger.Running == false {
+    if debugger.Status.Exited() {
+      result.ExitCode = debugger.Status.ExitStatus()
+    } else if debugger.Status.Signaled() {
+      result.Signal = debugger.Status.Signal().String()
+    }
+
+    return result
+  }
+
+  if debugger.Signal != 0 {
+    result.Signal = debugger.Signal.String()
+  }
+
+  if debugger.Hit != nil {
+    result.Breakpoint = debugger.Hit.Id
+  }
+
+  if pc, e := debugger.GetPC(); e == nil {
+    result.Address = pc
+  }
+
+  if debugger.Watch != nil {
+    result.Watchpoint = debugger.Watch
+
+    var start uint64
+
+    if _, offset, e := p.GetProcessSymbol(result.Address); e == nil {
+      start = result.Address - offset
+    }
+
+    result.Instruction, _ = debugger.GetPreviousInstruction(result.Address, start)
+  }
+
+  if name, offset, e := p.GetProcessSymbol(result.Address); e == nil {
+    result.Symbol = name
+
+    if offset > 0 {
+      result.Symbol = fmt.Sprintf("%s+%d", name, offset)
+    }
+  }
+
+  if library := debugger.GetLibraryAt(result.Address); library != nil {
+    result.Library = library.Name
+  }
+
+  return result
+}
+
+// replaces the executable of the session by the one of the process
+func (p *Analyzer) LoadExecutable(path string, file *elf.File) {
+  p.File.Close()
+
+  p.State = state.State {
+    Path: path, File: file, Output: p.Output}
+
+  p.LoadMappings()
+
+  p.Analyze()
+
+  if len(p.Data) == 0 { // the executable was removed or replaced
+    if data, e := ioutil.ReadFile("/proc/" + strconv.Itoa(p.debugger.Pid) + "/exe"); e == nil {
+      p.Data = data
+    }
+  }
+}
+
+// follows the process to the program it executed
+func (p *Analyzer) FollowExec() {
+  if p.debugger.File != p.File {
+    p.LoadExecutable(p.debugger.Path, p.debugger.File)
+  }
+
+  p.SetLive(true)
+
+  if p.Output != "json" {
+    fmt.Printf("Process %d is executing new program: %s\n", p.debugger.Pid, p.debugger.Path)
+  }
+}
+
+// reports why the process stopped and moves the current address to the pc
+func (p *Analyzer) ShowStop() {
+  p.Running = p.debugger.Running
+
+  if p.debugger.Running && p.debugger.Exec {
+    p.FollowExec()
+  }
+
+  stop := p.GetStop()
+
+  if stop.Running && len(stop.Library) > 0 {
+    p.SetLive(true) // the libraries are only in the memory of the process
+    p.Address = stop.Address
+  } else if stop.Running {
+    p.Address = p.ViewAddress(stop.Address - p.debugger.Bias)
+  } else {
+    p.SetLive(false)
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(stop)
+
+    return
+  }
+
+  if stop.Running == false {
+    if len(stop.Signal) > 0 {
+      fmt.Printf("Process %d killed by signal %s\n", stop.Pid, stop.Signal)
+    } else {
+      fmt.Printf("Process %d exited with status %d\n", stop.Pid, stop.ExitCode)
+    }
+
+    return
+  }
+
+  if len(p.debugger.Threads) > 1 {
+    fmt.Printf("[Thread %d] ", stop.Tid)
+  }
+
+  if stop.Watchpoint != nil {
+    p.ShowWatch(stop)
+  }
+
+  if stop.Breakpoint > 0 {
+    fmt.Printf("Breakpoint %d, ", stop.Breakpoint)
+  } else if len(stop.Signal) > 0 {
+    fmt.Printf("Process %d received signal %s, ", stop.Pid, stop.Signal)
+  }
+
+  if len(stop.Symbol) > 0 && len(stop.Library) > 0 {
+    fmt.Printf("0x%016x in %s from %s\n", stop.Address, stop.Symbol, stop.Library)
+  } else if len(stop.Symbol) > 0 {
+    fmt.Printf("0x%016x in %s\n", stop.Address, stop.Symbol)
+  } else {
+    fmt.Printf("0x%016x\n", stop.Address)
+  }
+
+  info := &info.Information {
+    State: &p.State}
+
+  info.ShowAssemble(p.Address, 1, "")
+}
+
+// splits the arguments of the process from the redirections (< in, > out, >> out, 2> err, 2>> err)
+func ParseRedirection(words []string) ([]string, debug.Redirection, error) {
+  var args []string
+  var redirection debug.Redirection
+
+  for i:=0; i<len(words); i++ {
+    word := words[i]
+
+    var target *string
+
+    for _, operator := range []string{"2>>", "2>", ">>", ">", "<"} {
+      if strings.HasPrefix(word, operator) {
+        if operator == "<" {
+          target = &redirection.Stdin
+        } else if strings.HasPrefix(operator, "2") {
+          target = &redirection.Stderr
+          redirection.AppendStderr = operator == "2>>"
+        } else {
+          target = &redirection.Stdout
+          redirection.AppendStdout = operator == ">>"
+        }
+
+        word = word[len(operator):]
+
+        break
+      }
+    }
+
+    if target == nil {
+      args = append(args, word)
+
+      continue
+    }
+
+    if len(word) == 0 {
+      if i + 1 >= len(words) {
+        return nil, redirection, err.InvalidOption
+      }
+
+      i = i + 1
+      word = words[i]
+    }
+
+    *target = word
+  }
+
+  return args, redirection, nil
+}
+
+// the process owns the terminal of jelf while it runs, so it is restored to its original state
+func (p *Analyzer) GiveTerminal() {
+  if p.term != nil && len(p.debugger.Tty) == 0 {
+    p.term.Suspend()
+  }
+}
+
+func (p *Analyzer) TakeTerminal() {
+  if p.term != nil && len(p.debugger.Tty) == 0 {
+    p.term.Resume()
+  }
+}
+
+func (p *Analyzer) RunProcess(words []string) error {
+  if p.Analyzed == false {
+    fmt.Println("Call 'analyze' before")
+
+    return err.CommandFailed
+  }
+
+  args, redirection, e := ParseRedirection(words)
+
+  if e != nil {
+    fmt.Println("invalid redirection")
+
+    return e
+  }
+
+  if e := p.debugger.Start(args, redirection); e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  p.Running = true
+
+  p.SetLive(true)
+
+  if p.Output != "json" {
+    fmt.Printf("Process %d started, load base 0x%x\n", p.debugger.Pid, p.debugger.Base)
+  }
+
+  p.GiveTerminal()
+
+  e = p.debugger.Continue()
+
+  p.TakeTerminal()
+
+  p.ShowStop()
+
+  return e
+}
+
+func (p *Analyzer) ShowEnvironment() {
+  if p.Output == "json" {
+    misc.ShowJson(p.debugger.Env)
+
+    return
+  }
+
+  for _, entry := range p.debugger.Env {
+    fmt.Println(entry)
+  }
+}
+
+// options of the process: env KEY=VALUE, cwd <dir>, tty <device|pty|inherit>, disable-randomization on|off
+func (p *Analyzer) SetProcessOption(args []string) error {
+  if args[0] == "disable-randomization" && len(args) == 2 && (args[1] == "on" || args[1] == "off") {
+    p.debugger.DisableRandomization = args[1] == "on"
+
+    return nil
+  } else if args[0] == "follow-fork" && len(args) == 2 && (args[1] == "parent" || args[1] == "child") {
+    p.debugger.FollowFork = args[1]
+
+    return nil
+  } else if args[0] == "env" {
+    if len(args) == 1 {
+      p.ShowEnvironment()
+
+      return nil
+    }
+
+    entry := strings.Join(args[1:], " ")
+
+    if i := strings.Index(entry, "="); i > 0 {
+      p.debugger.SetEnv(entry[:i], entry[i + 1:])
+
+      return nil
+    }
+  } else if args[0] == "cwd" && len(args) == 2 {
+    if info, e := os.Stat(args[1]); e != nil || info.IsDir() == false {
+      fmt.Println("invalid directory:", args[1])
+
+      return err.InvalidOption
+    }
+
+    p.debugger.Dir = args[1]
+
+    return nil
+  } else if args[0] == "tty" && len(args) == 2 {
+    if args[1] == "inherit" {
+      p.debugger.Tty = ""
+    } else {
+      p.debugger.Tty = args[1]
+    }
+
+    return nil
+  }
+
+  fmt.Println("invalid option")
+
+  return err.InvalidOption
+}
+
+// unset env [KEY], removing all the variables without a key
+func (p *Analyzer) UnsetOption(args []string) error {
+  if len(args) == 1 && args[0] == "env" {
+    p.debugger.Env = nil
+
+    return nil
+  }
+
+  if len(args) == 2 && args[0] == "env" {
+    p.debugger.UnsetEnv(args[1])
+
+    return nil
+  }
+
+  fmt.Println("invalid option")
+
+  return err.InvalidOption
+}
+
+// the breakpoints of the libraries are shown at their address in the process, if loaded
+func (p *Analyzer) ShowBreakpoints() {
+  var breakpoints []*debug.Breakpoint
+
+  for _, breakpoint := range p.debugger.Breakpoints {
+    if breakpoint.Temporary == false && breakpoint.Internal == false {
+      breakpoints = append(breakpoints, breakpoint)
+    }
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(breakpoints)
+
+    return
+  }
+
+  for _, breakpoint := range breakpoints {
+    if breakpoint.Library == "" {
+      fmt.Printf("%-4d 0x%016x%s, hits %d\n", breakpoint.Id, p.ViewAddress(breakpoint.Address), p.GetLocation(breakpoint.Address), breakpoint.Hits)
+    } else if location, e := p.debugger.GetLocation(breakpoint); e == nil {
+      fmt.Printf("%-4d 0x%016x%s in %s, hits %d\n", breakpoint.Id, location, p.GetProcessLocation(location), breakpoint.Library, breakpoint.Hits)
+    } else {
+      fmt.Printf("%-4d 0x%016x in %s (pending), hits %d\n", breakpoint.Id, breakpoint.Address, breakpoint.Library, breakpoint.Hits)
+    }
+  }
+}
+
+// the symbols of the executable come first, then the ones of the loaded libraries
+func (p *Analyzer) AddBreakpoint(text string) error {
+  addr, e := p.ResolveAddress(text)
+
+  if e != nil && p.debugger.Running {
+    addr, e = p.debugger.GetLibrarySymbolAddress(text)
+  }
+
+  if e != nil {
+    fmt.Println("address not found")
+
+    return e
+  }
+
+  var breakpoint *debug.Breakpoint
+
+  if p.debugger.Running && p.debugger.GetLibraryAt(addr) != nil {
+    breakpoint, e = p.debugger.AddBreakpointAt(addr)
+  } else {
+    breakpoint, e = p.debugger.AddBreakpoint(p.ToFileAddress(addr))
+  }
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(breakpoint)
+
+    return nil
+  }
+
+  if breakpoint.Library != "" {
+    fmt.Printf("Breakpoint %d at 0x%016x%s in %s\n", breakpoint.Id, addr, p.GetProcessLocation(addr), breakpoint.Library)
+
+    return nil
+  }
+
+  fmt.Printf("Breakpoint %d at 0x%016x%s\n", breakpoint.Id, p.ViewAddress(breakpoint.Address), p.GetLocation(breakpoint.Address))
+
+  return nil
+}
+
+func (p *Analyzer) DeleteBreakpoints(args []string) error {
+  if len(args) == 0 {
+    for _, breakpoint := range append([]*debug.Breakpoint{}, p.debugger.Breakpoints...) {
+      if breakpoint.Internal == false {
+        p.debugger.Delete(breakpoint)
+      }
+    }
+
+    for len(p.debugger.Watchpoints) > 0 {
+      p.debugger.DeleteWatchpoint(p.debugger.Watchpoints[0])
+    }
+
+    return nil
+  }
+
+  for _, arg := range args {
+    id, e := strconv.Atoi(arg)
+
+    if e != nil {
+      fmt.Println("invalid breakpoint:", arg)
+
+      return err.InvalidOption
+    }
+
+    breakpoint := p.debugger.GetBreakpointById(id)
+
+    // watchpoints share the numbers of the breakpoints
+    if watchpoint := p.debugger.GetWatchpointById(id); breakpoint == nil && watchpoint != nil {
+      p.debugger.DeleteWatchpoint(watchpoint)
+
+      continue
+    }
+
+    if breakpoint == nil {
+      fmt.Println("breakpoint not found:", arg)
+
+      return err.BreakpointNotFound
+    }
+
+    p.debugger.Delete(breakpoint)
+  }
+
+  return nil
+}
+
+// executes the command 'steps' times, stopping early at breakpoints and signals
+func (p *Analyzer) StepProcess(command string, steps int) error {
+  if p.debugger.Running == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  var e error
+
+  p.GiveTerminal()
+
+  for i:=0; i<steps; i++ {
+    if command == "continue" {
+      e = p.debugger.Continue()
+    } else if command == "stepi" {
+      e = p.debugger.Step()
+    } else if command == "nexti" {
+      e = p.debugger.Next()
+    } else if command == "finish" {
+      var start uint64
+
+      if pc, e := p.debugger.GetPC(); e == nil {
+        if _, offset, e := p.GetProcessSymbol(pc); e == nil {
+          start = pc - offset
+        }
+      }
+
+      e = p.debugger.Finish(start)
+    }
+
+    if e != nil || p.debugger.Running == false || p.debugger.Hit != nil || p.debugger.Watch != nil || p.debugger.Signal != 0 {
+      break
+    }
+  }
+
+  p.TakeTerminal()
+
+  if e != nil {
+    fmt.Println(e)
+  }
+
+  p.ShowStop()
+
+  if command == "finish" && e == nil && p.debugger.Running && p.debugger.Hit == nil && p.debugger.Watch == nil && p.debugger.Signal == 0 && p.Output != "json" {
+    if regs, e := p.debugger.GetRegisters(); e == nil {
+      fmt.Printf("Value returned: 0x%x (%d)\n", regs.Rax, int64(regs.Rax))
+    }
+  }
+
+  return e
+}
+
+// replaces the binary by the executable of the process, analyzing it before the attach
+func (p *Analyzer) AttachProcess(pid int) error {
+  if p.debugger.Running {
+    fmt.Println("process is already running")
+
+    return err.AlreadyRunning
+  }
+
+  exe := "/proc/" + strconv.Itoa(pid) + "/exe"
+
+  path, e := os.Readlink(exe)
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  file, e := elf.Open(exe)
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  debugger := debug.NewDebugger(path, file)
+
+  if e := debugger.Attach(pid); e != nil {
+    file.Close()
+
+    fmt.Println(e)
+
+    return e
+  }
+
+  p.debugger = debugger
+
+  p.LoadExecutable(path, file)
+
+  p.Running = true
+
+  p.SetLive(true)
+
+  if p.Output != "json" {
+    fmt.Printf("Attached to process %d (%s), load base 0x%x\n", pid, path, debugger.Base)
+  }
+
+  p.ShowStop()
+
+  return nil
+}
+
+func (p *Analyzer) DetachProcess() error {
+  if p.debugger.Attached == false {
+    fmt.Println("process is not attached")
+
+    return err.NotRunning
+  }
+
+  pid := p.debugger.Pid
+
+  e := p.debugger.Detach()
+
+  p.Running = false
+
+  p.SetLive(false)
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output != "json" {
+    fmt.Printf("Detached from process %d\n", pid)
+  }
+
+  return nil
+}
+
+func (p *Analyzer) ShowMaps() error {
+  maps, e := p.debugger.GetMaps()
+
+  if p.IsCore() {
+    maps, e = p.core.GetMaps(), nil
+  } else if p.debugger.Running == false || e != nil {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(maps)
+
+    return nil
+  }
+
+  for _, m := range maps {
+    fmt.Printf("0x%016x - 0x%016x %s 0x%08x %s\n", m.Start, m.End, m.Perms, m.Offset, m.Path)
+  }
+
+  return nil
+}
+
+func (p *Analyzer) KillProcess() error {
+  if e := p.debugger.Kill(); e != nil {
+    fmt.Println("process is not running")
+
+    return e
+  }
+
+  p.ShowStop()
+
+  return nil
+}
+
+// writes the hex bytes (ex: 90 90 c3) or the 64 bits value of '=expression' at the address
+func (p *Analyzer) WriteProcess(args []string) error {
+  if p.debugger.Running == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  if len(args) < 2 {
+    fmt.Println("invalid arguments")
+
+    return err.InvalidOption
+  }
+
+  addr, e := p.ResolveAddress(args[0])
+
+  if e != nil {
+    fmt.Println("address not found")
+
+    return e
+  }
+
+  var data []byte
+
+  value := strings.Join(args[1:], "")
+
+  if strings.HasPrefix(value, "=") {
+    i, e := p.EvaluateExpression(value[1:])
+
+    if e != nil {
+      fmt.Println("invalid expression: ", e)
+
+      return e
+    }
+
+    // a word of the target
+    if p.File.Class == elf.ELFCLASS32 {
+      data = make([]byte, 4)
+
+      p.File.ByteOrder.PutUint32(data, uint32(i))
+    } else {
+      data = make([]byte, 8)
+
+      p.File.ByteOrder.PutUint64(data, i)
+    }
+  } else {
+    data, e = hex.DecodeString(strings.TrimPrefix(value, "0x"))
+
+    if e != nil || len(data) == 0 {
+      fmt.Println("invalid bytes: ", value)
+
+      return err.InvalidOption
+    }
+  }
+
+  if e := p.debugger.WriteMemory(p.ToFileAddress(addr) + p.debugger.Bias, data); e != nil {
+    fmt.Printf("Address:[0x%08x] is not writable\n", addr)
+
+    return e
+  }
+
+  return nil
+}
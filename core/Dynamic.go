@@ -0,0 +1,179 @@
+package core
+
+import (
+  "bytes"
+  "debug/elf"
+  "fmt"
+  "strings"
+
+  "jelf/core/state"
+)
+
+var dynamicFlags1 = []struct {
+  Flag uint64
+  Name string
+}{
+  {0x00000001, "NOW"},
+  {0x00000002, "GLOBAL"},
+  {0x00000004, "GROUP"},
+  {0x00000008, "NODELETE"},
+  {0x00000010, "LOADFLTR"},
+  {0x00000020, "INITFIRST"},
+  {0x00000040, "NOOPEN"},
+  {0x00000080, "ORIGIN"},
+  {0x00000100, "DIRECT"},
+  {0x00000400, "INTERPOSE"},
+  {0x00000800, "NODEFLIB"},
+  {0x00001000, "NODUMP"},
+  {0x00002000, "CONFALT"},
+  {0x00004000, "ENDFILTEE"},
+  {0x00008000, "DISPRELDNE"},
+  {0x00010000, "DISPRELPND"},
+  {0x00020000, "NODIRECT"},
+  {0x00040000, "IGNMULDEF"},
+  {0x00080000, "NOKSYMS"},
+  {0x00100000, "NOHDR"},
+  {0x00200000, "EDITED"},
+  {0x00400000, "NORELOC"},
+  {0x00800000, "SYMINTPOSE"},
+  {0x01000000, "GLOBAUDIT"},
+  {0x02000000, "SINGLETON"},
+  {0x04000000, "STUB"},
+  {0x08000000, "PIE"}}
+
+func (p *Analyzer) GetDynamicData() []byte {
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_DYNAMIC {
+      data := make([]byte, prog.Filesz)
+
+      if _, e := prog.ReadAt(data, 0); e == nil {
+        return data
+      }
+    }
+  }
+
+  section := p.File.Section(".dynamic")
+
+  if section != nil {
+    data, e := section.Data()
+
+    if e == nil {
+      return data
+    }
+  }
+
+  return nil
+}
+
+func (p *Analyzer) GetDynamicString(strtab []byte, offset uint64) string {
+  if offset >= uint64(len(strtab)) {
+    return fmt.Sprintf("<invalid string offset 0x%x>", offset)
+  }
+
+  end := bytes.IndexByte(strtab[offset:], 0)
+
+  if end < 0 {
+    return string(strtab[offset:])
+  }
+
+  return string(strtab[offset:offset + uint64(end)])
+}
+
+func (p *Analyzer) GetDynamicText(entry state.DynamicEntry, strtab []byte) string {
+  switch entry.Tag {
+    case elf.DT_NEEDED:
+      return "Shared library: [" + p.GetDynamicString(strtab, entry.Value) + "]"
+    case elf.DT_SONAME:
+      return "Library soname: [" + p.GetDynamicString(strtab, entry.Value) + "]"
+    case elf.DT_RPATH:
+      return "Library rpath: [" + p.GetDynamicString(strtab, entry.Value) + "]"
+    case elf.DT_RUNPATH:
+      return "Library runpath: [" + p.GetDynamicString(strtab, entry.Value) + "]"
+    case elf.DT_AUXILIARY, elf.DT_FILTER:
+      return "Filter library: [" + p.GetDynamicString(strtab, entry.Value) + "]"
+    case elf.DT_FLAGS:
+      var names []string
+
+      for _, flag := range []elf.DynFlag{elf.DF_ORIGIN, elf.DF_SYMBOLIC, elf.DF_TEXTREL, elf.DF_BIND_NOW, elf.DF_STATIC_TLS} {
+        if entry.Value & uint64(flag) != 0 {
+          names = append(names, strings.TrimPrefix(flag.String(), "DF_"))
+        }
+      }
+
+      return strings.Join(names, " ")
+    case elf.DT_FLAGS_1:
+      var names []string
+
+      for _, flag := range dynamicFlags1 {
+        if entry.Value & flag.Flag != 0 {
+          names = append(names, flag.Name)
+        }
+      }
+
+      return "Flags: " + strings.Join(names, " ")
+    case elf.DT_PLTREL:
+      return strings.TrimPrefix(elf.DynTag(entry.Value).String(), "DT_")
+    case elf.DT_PLTRELSZ, elf.DT_RELASZ, elf.DT_RELAENT, elf.DT_RELSZ, elf.DT_RELENT, elf.DT_STRSZ, elf.DT_SYMENT,
+      elf.DT_INIT_ARRAYSZ, elf.DT_FINI_ARRAYSZ, elf.DT_PREINIT_ARRAYSZ:
+      return fmt.Sprintf("%d (bytes)", entry.Value)
+    case elf.DT_VERNEEDNUM, elf.DT_VERDEFNUM, elf.DynTag(0x6ffffff9), elf.DynTag(0x6ffffffa): // RELACOUNT and RELCOUNT
+      return fmt.Sprintf("%d", entry.Value)
+    case elf.DT_BIND_NOW, elf.DT_TEXTREL, elf.DT_SYMBOLIC, elf.DT_NULL:
+      return ""
+  }
+
+  return fmt.Sprintf("0x%x", entry.Value)
+}
+
+func (p *Analyzer) LoadDynamic() {
+  p.Dynamic = nil
+
+  data := p.GetDynamicData()
+
+  if data == nil {
+    return
+  }
+
+  size := 16
+
+  if p.File.Class == elf.ELFCLASS32 {
+    size = 8
+  }
+
+  for i:=0; i+size<=len(data); i+=size {
+    var entry state.DynamicEntry
+
+    if size == 16 {
+      entry.Tag = elf.DynTag(p.File.ByteOrder.Uint64(data[i:]))
+      entry.Value = p.File.ByteOrder.Uint64(data[i + 8:])
+    } else {
+      entry.Tag = elf.DynTag(int32(p.File.ByteOrder.Uint32(data[i:])))
+      entry.Value = uint64(p.File.ByteOrder.Uint32(data[i + 4:]))
+    }
+
+    p.Dynamic = append(p.Dynamic, entry)
+
+    if entry.Tag == elf.DT_NULL {
+      break
+    }
+  }
+
+  // the string table is referenced by address, so it works without section headers
+  var strtab []byte
+
+  if entry, e := p.GetDynamic(elf.DT_STRTAB); e == nil {
+    if size, e := p.GetDynamic(elf.DT_STRSZ); e == nil {
+      strtab, _ = p.ReadVirtual(entry.Value, size.Value)
+    }
+  }
+
+  if strtab == nil {
+    if section := p.File.Section(".dynstr"); section != nil {
+      strtab, _ = section.Data()
+    }
+  }
+
+  for i := range p.Dynamic {
+    p.Dynamic[i].Text = p.GetDynamicText(p.Dynamic[i], strtab)
+  }
+}
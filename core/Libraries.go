@@ -0,0 +1,54 @@
+package core
+
+import (
+  "fmt"
+
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type LibraryInfo struct {
+  Name string `json:"name"`
+  Base uint64 `json:"base"`
+  Start uint64 `json:"start"`
+  End uint64 `json:"end"`
+  Symbols int `json:"symbols"`
+}
+
+// shows the libraries loaded by the dynamic linker, with their load base
+func (p *Analyzer) ShowLibraries() error {
+  if p.debugger.Running == false && p.IsCore() == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  list := p.debugger.Libraries
+
+  if p.IsCore() {
+    list = p.core.Libraries
+  } else {
+    p.debugger.LoadLibraries()
+
+    list = p.debugger.Libraries
+  }
+
+  var libraries []LibraryInfo
+
+  for _, library := range list {
+    libraries = append(libraries, LibraryInfo{
+      Name: library.Name, Base: library.Base, Start: library.Start, End: library.End, Symbols: len(library.Symbols)})
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(libraries)
+
+    return nil
+  }
+
+  for _, library := range libraries {
+    fmt.Printf("0x%016x 0x%016x-0x%016x %6d %s\n", library.Base, library.Start, library.End, library.Symbols, library.Name)
+  }
+
+  return nil
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+  "debug/dwarf"
+  "fmt"
+  "sort"
+
+  "jelf/core/misc"
+  "jelf/core/state"
+)
+
+type LineInfo struct {
+  Address uint64 `json:"address"`
+  File string `json:"file"`
+  Line int `json:"line"`
+  Start uint64 `json:"start"`
+  End uint64 `json:"end"`
+  Source string `json:"source,omitempty"`
+}
+
+// loads the line tables of the compilation units, empty without dwarf
+func (p *Analyzer) LoadLines() {
+  p.Lines = []state.SourceLine{}
+
+  data, e := p.File.DWARF()
+
+  if e != nil {
+    return
+  }
+
+  reader := data.Reader()
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil {
+      break
+    }
+
+    if entry.Tag != dwarf.TagCompileUnit {
+      reader.SkipChildren()
+
+      continue
+    }
+
+    lines, e := data.LineReader(entry)
+
+    if e != nil || lines == nil {
+      continue
+    }
+
+    var line dwarf.LineEntry
+
+    for lines.Next(&line) == nil {
+      row := state.SourceLine{
+        Address: line.Address, Line: line.Line, End: line.EndSequence}
+
+      if line.File != nil {
+        row.File = line.File.Name
+      }
+
+      p.Lines = append(p.Lines, row)
+    }
+
+    reader.SkipChildren()
+  }
+
+  // the end of a sequence goes before the rows starting at the same address
+  sort.SliceStable(p.Lines, func(i, j int) bool {
+    if p.Lines[i].Address == p.Lines[j].Address {
+      return p.Lines[i].End && p.Lines[j].End == false
+    }
+
+    return p.Lines[i].Address < p.Lines[j].Address
+  })
+}
+
+func (p *Analyzer) GetLineAddress(file string, line int) (uint64, error) {
+  if p.Lines == nil {
+    p.LoadLines()
+  }
+
+  return p.State.GetLineAddress(file, line)
+}
+
+// info line <address/file:line>, the range of the code of the line, in the
+// addresses of the current view
+func (p *Analyzer) ShowLineInfo(text string) error {
+  addr, e := p.ResolveAddress(text)
+
+  if e != nil {
+    fmt.Println("address not found")
+
+    return e
+  }
+
+  fileAddress := p.ToFileAddress(addr)
+
+  if p.Lines == nil {
+    p.LoadLines()
+  }
+
+  line, end, e := p.GetSourceRange(fileAddress)
+
+  if e != nil {
+    fmt.Printf("No line information for address 0x%x\n", addr)
+
+    return e
+  }
+
+  result := LineInfo{
+    Address: p.ViewAddress(fileAddress), File: line.File, Line: line.Line, Start: p.ViewAddress(line.Address), End: p.ViewAddress(end)}
+
+  result.Source, _ = p.GetSourceText(line.File, line.Line)
+
+  if p.Output == "json" {
+    misc.ShowJson(result)
+
+    return nil
+  }
+
+  fmt.Printf("Line %d of \"%s\" starts at address 0x%x and ends at 0x%x\n", result.Line, result.File, result.Start, result.End)
+
+  if len(result.Source) > 0 {
+    fmt.Printf("%d\t%s\n", result.Line, result.Source)
+  }
+
+  return nil
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+  "debug/elf"
+
+  "jelf/core/state"
+)
+
+func (p *Analyzer) ParseNotes(data []byte, align uint64) []state.Note {
+  return state.ParseNotes(p.File.ByteOrder, data, align)
+}
+
+func (p *Analyzer) GetNotes() []state.Note {
+  var notes []state.Note
+
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_NOTE {
+      if data, e := p.GetSegmentData(prog); e == nil {
+        notes = append(notes, p.ParseNotes(data, prog.Align)...)
+      }
+    }
+  }
+
+  if len(p.File.Progs) > 0 {
+    return notes
+  }
+
+  for _, section := range p.File.Sections {
+    if section.Type == elf.SHT_NOTE {
+      if data, e := section.Data(); e == nil {
+        notes = append(notes, p.ParseNotes(data, section.Addralign)...)
+      }
+    }
+  }
+
+  return notes
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+  "debug/elf"
+  "strings"
+
+  "golang.org/x/arch/x86/x86asm"
+)
+
+func (p *Analyzer) IsImportRelocation(typeName string) bool {
+  return strings.HasSuffix(typeName, "_JUMP_SLOT") || strings.HasSuffix(typeName, "_JMP_SLOT") || strings.HasSuffix(typeName, "_GLOB_DAT")
+}
+
+func (p *Analyzer) GetGotBase() uint64 {
+  if section := p.File.Section(".got.plt"); section != nil {
+    return section.Addr
+  }
+
+  if section := p.File.Section(".got"); section != nil {
+    return section.Addr
+  }
+
+  return 0
+}
+
+// finds the got slot read by the indirect jump of each x86 stub
+func (p *Analyzer) LoadX86Plt(section *elf.Section) {
+  data, e := section.Data()
+
+  if e != nil {
+    return
+  }
+
+  mode := 64
+
+  if p.File.Class == elf.ELFCLASS32 {
+    mode = 32
+  }
+
+  entsize := section.Entsize
+
+  if entsize == 0 {
+    entsize = 16
+  }
+
+  var offset uint64
+
+  for offset < uint64(len(data)) {
+    ins, e := x86asm.Decode(data[offset:], mode)
+
+    if e != nil {
+      offset = offset + 1
+
+      continue
+    }
+
+    addr := section.Addr + offset
+    next := addr + uint64(ins.Len)
+
+    offset = offset + uint64(ins.Len)
+
+    if ins.Op != x86asm.JMP {
+      continue
+    }
+
+    mem, ok := ins.Args[0].(x86asm.Mem)
+
+    if ok == false || mem.Index != 0 {
+      continue
+    }
+
+    var slot uint64
+
+    if mem.Base == x86asm.RIP {
+      slot = uint64(int64(next) + mem.Disp)
+    } else if mem.Base == x86asm.EBX {
+      slot = uint64(int64(p.GetGotBase()) + mem.Disp) // i386 pic stubs are relative to the got
+    } else if mem.Base == 0 {
+      slot = uint64(uint32(mem.Disp))
+    } else {
+      continue
+    }
+
+    name, found := p.PltSymbols[slot]
+
+    if found && strings.HasSuffix(name, "@got") {
+      stub := section.Addr + ((addr - section.Addr) / entsize) * entsize
+
+      if _, exists := p.PltSymbols[stub]; exists == false {
+        p.PltSymbols[stub] = strings.TrimSuffix(name, "@got") + "@plt"
+      }
+    }
+  }
+}
+
+// arm stubs are not decoded, the jump slots follow the order of the entries
+func (p *Analyzer) LoadOrderedPlt(section *elf.Section, header, entsize uint64) {
+  addr := section.Addr + header
+
+  for _, relocation := range p.Relocations {
+    if strings.HasSuffix(relocation.TypeName, "_JUMP_SLOT") && len(relocation.Symbol) > 0 {
+      if addr + entsize > section.Addr + section.Size {
+        break
+      }
+
+      p.PltSymbols[addr] = relocation.Symbol + "@plt"
+
+      addr = addr + entsize
+    }
+  }
+}
+
+func (p *Analyzer) LoadPlt() {
+  p.PltSymbols = make(map[uint64]string)
+
+  for _, relocation := range p.Relocations {
+    if p.IsImportRelocation(relocation.TypeName) && len(relocation.Symbol) > 0 {
+      p.PltSymbols[relocation.Address] = relocation.Symbol + "@got"
+    }
+  }
+
+  for _, name := range []string{".plt", ".plt.sec", ".plt.got"} {
+    section := p.File.Section(name)
+
+    if section == nil {
+      continue
+    }
+
+    if p.File.Machine == elf.EM_X86_64 || p.File.Machine == elf.EM_386 {
+      p.LoadX86Plt(section)
+    } else if p.File.Machine == elf.EM_AARCH64 && name == ".plt" {
+      p.LoadOrderedPlt(section, 32, 16)
+    } else if p.File.Machine == elf.EM_ARM && name == ".plt" {
+      p.LoadOrderedPlt(section, 20, 12)
+    }
+  }
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+  "encoding/binary"
+  "fmt"
+  "math"
+  "regexp"
+  "strconv"
+  "strings"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type Register struct {
+  Name string `json:"name"`
+  Value string `json:"value"`
+  Description string `json:"description,omitempty"`
+}
+
+func (p *Analyzer) GetGeneralRegisters() ([]Register, error) {
+  var result []Register
+
+  for _, name := range debug.RegisterNames {
+    value, e := p.GetRegister(name)
+
+    if e != nil {
+      return nil, e
+    }
+
+    register := Register{
+      Name: name, Value: fmt.Sprintf("0x%x", value)}
+
+    if name == "eflags" {
+      register.Description = debug.GetFlagsDescription(value)
+    } else if name == "rip" {
+      register.Description = strings.TrimPrefix(p.GetProcessLocation(value), " ")
+    } else if name != "orig_rax" {
+      register.Description = strconv.FormatInt(int64(value), 10)
+    }
+
+    result = append(result, register)
+  }
+
+  return result, nil
+}
+
+// kind is one of fp, sse or avx
+func (p *Analyzer) GetVectorRegisters(kind string) ([]Register, error) {
+  fp, e := p.GetFPRegisters()
+
+  if e != nil {
+    return nil, e
+  }
+
+  var result []Register
+
+  if kind == "fp" {
+    result = append(result,
+      Register{Name: "fcw", Value: fmt.Sprintf("0x%04x", fp.Control)},
+      Register{Name: "fsw", Value: fmt.Sprintf("0x%04x", fp.Status), Description: fmt.Sprintf("top %d", (fp.Status >> 11) & 0x7)},
+      Register{Name: "ftw", Value: fmt.Sprintf("0x%04x", fp.Tag)})
+
+    for i:=0; i<8; i++ {
+      result = append(result, Register{
+        Name: fmt.Sprintf("st%d", i), Value: fmt.Sprintf("0x%x", reverse(fp.St[i][:])), Description: fmt.Sprint(debug.ExtendedToFloat(fp.St[i]))})
+    }
+  } else if kind == "sse" {
+    result = append(result, Register{Name: "mxcsr", Value: fmt.Sprintf("0x%08x", fp.Mxcsr)})
+
+    for i:=0; i<16; i++ {
+      xmm := fp.Xmm[i][:]
+
+      result = append(result, Register{
+        Name: fmt.Sprintf("xmm%d", i), Value: fmt.Sprintf("0x%x", reverse(xmm)),
+        Description: fmt.Sprintf("f64 {%g, %g}", float64FromBits(xmm[0:]), float64FromBits(xmm[8:]))})
+    }
+  } else if kind == "avx" {
+    if fp.Avx == false {
+      return nil, err.RegisterNotFound
+    }
+
+    for i:=0; i<16; i++ {
+      result = append(result, Register{
+        Name: fmt.Sprintf("ymm%d", i), Value: fmt.Sprintf("0x%x%x", reverse(fp.Ymm[i][:]), reverse(fp.Xmm[i][:]))})
+    }
+  } else {
+    return nil, err.InvalidOption
+  }
+
+  return result, nil
+}
+
+// registers are stored in little endian, but shown as a single number
+func reverse(data []byte) []byte {
+  result := make([]byte, len(data))
+
+  for i, b := range data {
+    result[len(data) - 1 - i] = b
+  }
+
+  return result
+}
+
+func float64FromBits(data []byte) float64 {
+  return math.Float64frombits(binary.LittleEndian.Uint64(data))
+}
+
+func (p *Analyzer) ShowRegisters(kind string) error {
+  if p.debugger.Running == false && p.IsCore() == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  var registers []Register
+  var e error
+
+  if kind == "" || kind == "general" {
+    registers, e = p.GetGeneralRegisters()
+  } else if kind == "all" {
+    for _, k := range []string{"general", "fp", "sse", "avx"} {
+      var r []Register
+
+      if k == "general" {
+        r, e = p.GetGeneralRegisters()
+      } else {
+        r, e = p.GetVectorRegisters(k)
+      }
+
+      if e == err.RegisterNotFound {
+        e = nil // no avx state
+      }
+
+      registers = append(registers, r...)
+    }
+  } else {
+    registers, e = p.GetVectorRegisters(kind)
+  }
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(registers)
+
+    return nil
+  }
+
+  for _, register := range registers {
+    fmt.Printf("%-10s %-20s %s\n", register.Name, register.Value, register.Description)
+  }
+
+  return nil
+}
+
+// replaces $name by the value of the register, so it can be used by expressions
+func (p *Analyzer) ExpandRegisters(text string) (string, error) {
+  var failure error
+
+  r, _ := regexp.Compile(`\$(\w+)`)
+
+  result := r.ReplaceAllStringFunc(text, func(match string) string {
+    value, e := p.GetRegister(match[1:])
+
+    if e != nil {
+      failure = e
+
+      return match
+    }
+
+    return strconv.FormatUint(value, 10)
+  })
+
+  return result, failure
+}
+
+func (p *Analyzer) EvaluateExpression(text string) (uint64, error) {
+  text, e := p.ExpandRegisters(text)
+
+  if e != nil {
+    return 0, e
+  }
+
+  // large values lose precision as floats
+  if i, e := strconv.ParseUint(strings.TrimSpace(text), 0, 64); e == nil {
+    return i, nil
+  }
+
+  r, e := misc.ParseAndEval(text)
+
+  if e != nil {
+    return 0, e
+  }
+
+  return uint64(int64(r)), nil
+}
+
+// set $reg = expression
+func (p *Analyzer) SetRegister(args []string) error {
+  parts := strings.SplitN(strings.Join(args, " "), "=", 2)
+
+  if len(parts) != 2 {
+    fmt.Println("invalid option")
+
+    return err.InvalidOption
+  }
+
+  name := strings.TrimPrefix(strings.TrimSpace(parts[0]), "$")
+
+  value, e := p.EvaluateExpression(parts[1])
+
+  if e != nil {
+    fmt.Println("invalid expression: ", e)
+
+    return e
+  }
+
+  if e := p.debugger.SetRegister(name, value); e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if name == "rip" || name == "eip" || name == "pc" {
+    p.Address = p.ViewAddress(p.ToFileAddress(value))
+  }
+
+  return nil
+}
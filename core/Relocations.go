@@ -0,0 +1,128 @@
+package core
+
+import (
+  "debug/elf"
+  "fmt"
+
+  "jelf/core/state"
+)
+
+func (p *Analyzer) GetRelocationTypeName(t uint32) string {
+  switch p.File.Machine {
+    case elf.EM_X86_64:
+      return elf.R_X86_64(t).String()
+    case elf.EM_386:
+      return elf.R_386(t).String()
+    case elf.EM_AARCH64:
+      return elf.R_AARCH64(t).String()
+    case elf.EM_ARM:
+      return elf.R_ARM(t).String()
+    case elf.EM_PPC:
+      return elf.R_PPC(t).String()
+    case elf.EM_PPC64:
+      return elf.R_PPC64(t).String()
+    case elf.EM_MIPS:
+      return elf.R_MIPS(t).String()
+    case elf.EM_RISCV:
+      return elf.R_RISCV(t).String()
+    case elf.EM_SPARC, elf.EM_SPARC32PLUS, elf.EM_SPARCV9:
+      return elf.R_SPARC(t).String()
+    case elf.EM_S390:
+      return elf.R_390(t).String()
+  }
+
+  return fmt.Sprintf("R_%d", t)
+}
+
+func (p *Analyzer) GetRelocationSymbols(section *elf.Section) []elf.Symbol {
+  if section.Link == 0 || int(section.Link) >= len(p.File.Sections) {
+    return nil
+  }
+
+  if p.File.Sections[section.Link].Type == elf.SHT_DYNSYM {
+    return p.DynamicSymbols
+  }
+
+  return p.Symbols
+}
+
+func (p *Analyzer) LoadRelocations() {
+  p.Relocations = nil
+
+  for _, section := range p.File.Sections {
+    if section.Type != elf.SHT_REL && section.Type != elf.SHT_RELA {
+      continue
+    }
+
+    data, e := section.Data()
+
+    if e != nil {
+      continue
+    }
+
+    symbols := p.GetRelocationSymbols(section)
+
+    // relocatable objects use offsets inside of the target section
+    var base uint64
+
+    if p.File.Type == elf.ET_REL && section.Info > 0 && int(section.Info) < len(p.File.Sections) {
+      base = p.File.Sections[section.Info].Offset
+    }
+
+    size := 8
+
+    if p.File.Class == elf.ELFCLASS64 {
+      size = 16
+    }
+
+    if section.Type == elf.SHT_RELA {
+      size = size + size/2
+    }
+
+    for i:=0; i+size<=len(data); i+=size {
+      relocation := state.Relocation{
+        Section: section.Name, HasAddend: section.Type == elf.SHT_RELA}
+
+      var index uint32
+
+      if p.File.Class == elf.ELFCLASS64 {
+        info := p.File.ByteOrder.Uint64(data[i + 8:])
+
+        relocation.Offset = p.File.ByteOrder.Uint64(data[i:])
+        relocation.Type = uint32(info)
+        index = uint32(info >> 32)
+
+        if relocation.HasAddend {
+          relocation.Addend = int64(p.File.ByteOrder.Uint64(data[i + 16:]))
+        }
+      } else {
+        info := p.File.ByteOrder.Uint32(data[i + 4:])
+
+        relocation.Offset = uint64(p.File.ByteOrder.Uint32(data[i:]))
+        relocation.Type = info & 0xff
+        index = info >> 8
+
+        if relocation.HasAddend {
+          relocation.Addend = int64(int32(p.File.ByteOrder.Uint32(data[i + 8:])))
+        }
+      }
+
+      relocation.Address = base + relocation.Offset
+      relocation.TypeName = p.GetRelocationTypeName(relocation.Type)
+
+      // debug/elf drops the null symbol, so index n is found at n - 1
+      if index > 0 && int(index) <= len(symbols) {
+        symbol := symbols[index - 1]
+
+        relocation.Symbol = symbol.Name
+        relocation.SymbolValue = symbol.Value
+
+        if len(symbol.Name) == 0 && elf.ST_TYPE(symbol.Info) == elf.STT_SECTION && int(symbol.Section) < len(p.File.Sections) {
+          relocation.Symbol = p.File.Sections[symbol.Section].Name
+        }
+      }
+
+      p.Relocations = append(p.Relocations, relocation)
+    }
+  }
+}
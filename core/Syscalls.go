@@ -0,0 +1,154 @@
+package core
+
+import (
+  "fmt"
+  "strings"
+  "syscall"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+// the process does not return from these, so they are shown at the entry
+var noReturnSyscalls = []string{"exit", "exit_group"}
+
+func containsName(names []string, name string) bool {
+  for _, n := range names {
+    if n == name {
+      return true
+    }
+  }
+
+  return false
+}
+
+func (p *Analyzer) ShowSyscall(call *debug.Syscall) {
+  if len(p.debugger.Threads) > 1 && p.Output != "json" {
+    fmt.Printf("[%d] ", p.debugger.Tid)
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(call)
+  } else if call.Returned || containsName(noReturnSyscalls, call.Name) {
+    fmt.Println(call.String())
+  } else {
+    fmt.Println(call.Name + "(" + strings.Join(call.Args, ", ") + ") ...")
+  }
+}
+
+// trace syscalls [name,...] [stop name,...] : continues the process showing the
+// syscalls of the filter (all of them without it), until one of the stop list is entered
+func (p *Analyzer) TraceSyscalls(words []string) error {
+  if len(words) == 0 || words[0] != "syscalls" {
+    fmt.Println("invalid option")
+
+    return err.InvalidOption
+  }
+
+  var filter, stops []string
+
+  for i:=1; i<len(words); i++ {
+    if words[i] == "stop" && i + 1 < len(words) {
+      stops = append(stops, strings.Split(words[i + 1], ",")...)
+      i++
+    } else if words[i] != "all" {
+      filter = append(filter, strings.Split(words[i], ",")...)
+    }
+  }
+
+  if p.debugger.Running == false {
+    if p.Analyzed == false {
+      fmt.Println("Call 'analyze' before")
+
+      return err.CommandFailed
+    }
+
+    if e := p.debugger.Start(nil, debug.Redirection{}); e != nil {
+      fmt.Println(e)
+
+      return e
+    }
+
+    p.Running = true
+
+    p.SetLive(true)
+
+    if p.Output != "json" {
+      fmt.Printf("Process %d started, load base 0x%x\n", p.debugger.Pid, p.debugger.Base)
+    }
+  }
+
+  var e error
+  var stopped *debug.Syscall
+
+  p.GiveTerminal()
+
+  for {
+    if e = p.debugger.ContinueSyscall(); e != nil || p.debugger.Running == false || p.debugger.Hit != nil || p.debugger.Watch != nil {
+      break
+    }
+
+    if p.debugger.Exec {
+      p.FollowExec()
+
+      continue
+    }
+
+    if p.debugger.SyscallStop == false {
+      if p.debugger.Signal == 0 {
+        continue
+      }
+
+      if p.Output != "json" {
+        fmt.Printf("--- %s ---\n", p.debugger.Signal)
+      }
+
+      if p.debugger.Signal == syscall.SIGINT {
+        break
+      }
+
+      continue
+    }
+
+    call := p.debugger.Syscall
+
+    if call == nil {
+      continue
+    }
+
+    shown := len(filter) == 0 || containsName(filter, call.Name)
+
+    if p.debugger.InSyscall {
+      if containsName(stops, call.Name) {
+        stopped = call
+
+        break
+      }
+
+      if shown && containsName(noReturnSyscalls, call.Name) {
+        p.ShowSyscall(call)
+      }
+    } else if shown {
+      p.ShowSyscall(call)
+    }
+  }
+
+  p.TakeTerminal()
+
+  if e != nil {
+    fmt.Println(e)
+  }
+
+  if stopped != nil {
+    if p.Output != "json" {
+      fmt.Print("Stopped at syscall ")
+    }
+
+    p.ShowSyscall(stopped)
+  }
+
+  p.ShowStop()
+
+  return e
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+  "fmt"
+  "strconv"
+
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type ThreadInfo struct {
+  Tid int `json:"tid"`
+  Name string `json:"name"`
+  Address uint64 `json:"address"`
+  Symbol string `json:"symbol,omitempty"`
+  Current bool `json:"current"`
+}
+
+func (p *Analyzer) ShowThreads() error {
+  if p.debugger.Running == false && p.IsCore() == false {
+    fmt.Println("process is not running")
+
+    return err.NotRunning
+  }
+
+  var threads []ThreadInfo
+
+  if p.IsCore() {
+    threads = p.GetCoreThreads()
+  }
+
+  for _, thread := range p.debugger.Threads {
+    info := ThreadInfo{
+      Tid: thread.Tid, Name: p.debugger.GetThreadName(thread.Tid), Current: thread.Tid == p.debugger.Tid}
+
+    if pc, e := p.debugger.GetThreadPC(thread.Tid); e == nil {
+      info.Address = pc
+      info.Symbol = p.GetProcessLocation(pc)
+    }
+
+    threads = append(threads, info)
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(threads)
+
+    return nil
+  }
+
+  for _, thread := range threads {
+    mark := " "
+
+    if thread.Current {
+      mark = "*"
+    }
+
+    fmt.Printf("%s %-8d %-16s 0x%016x%s\n", mark, thread.Tid, thread.Name, thread.Address, thread.Symbol)
+  }
+
+  return nil
+}
+
+func (p *Analyzer) SwitchThread(text string) error {
+  tid, e := strconv.Atoi(text)
+
+  if e != nil {
+    fmt.Println("invalid thread:", text)
+
+    return err.InvalidOption
+  }
+
+  if p.IsCore() {
+    if e := p.core.SetThread(tid); e != nil {
+      fmt.Println(e)
+
+      return e
+    }
+
+    p.Address, _ = p.GetRegister("rip")
+
+    return p.ShowCore()
+  }
+
+  if e := p.debugger.SetThread(tid); e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  p.ShowStop()
+
+  return nil
+}
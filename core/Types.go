@@ -0,0 +1,620 @@
+package core
+
+import (
+  "debug/dwarf"
+  "fmt"
+  "sort"
+  "strings"
+
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type TypeInfo struct {
+  Kind string `json:"kind"`
+  Name string `json:"name"`
+  Size int64 `json:"size"`
+  Type string `json:"type,omitempty"` // the target of the typedefs
+}
+
+type MemberInfo struct {
+  Name string `json:"name"`
+  Type string `json:"type"`
+  Offset int64 `json:"offset"`
+  Size int64 `json:"size"`
+  BitSize int64 `json:"bit_size,omitempty"`
+}
+
+type EnumValue struct {
+  Name string `json:"name"`
+  Value int64 `json:"value"`
+}
+
+// the description of a type, a function or a variable
+type TypeDetails struct {
+  Kind string `json:"kind"`
+  Name string `json:"name"`
+  Size int64 `json:"size"`
+  Type string `json:"type,omitempty"` // the type of the variables and the return type of the functions
+  Typedefs []string `json:"typedefs,omitempty"` // the chain of the typedefs, up to the resolved type
+  Members []MemberInfo `json:"members,omitempty"`
+  Values []EnumValue `json:"values,omitempty"`
+  Params []MemberInfo `json:"params,omitempty"`
+  Variadic bool `json:"variadic,omitempty"`
+}
+
+var typeKinds = map[dwarf.Tag]string{
+  dwarf.TagBaseType: "base",
+  dwarf.TagStructType: "struct",
+  dwarf.TagUnionType: "union",
+  dwarf.TagClassType: "class",
+  dwarf.TagEnumerationType: "enum",
+  dwarf.TagTypedef: "typedef"}
+
+// the c declaration of a name of the type, like 'char *name[16]'
+func declaration(t dwarf.Type, name string) string {
+  switch t := t.(type) {
+    case *dwarf.ArrayType:
+      if t.Count < 0 {
+        return declaration(t.Type, name + "[]")
+      }
+
+      return declaration(t.Type, fmt.Sprintf("%s[%d]", name, t.Count))
+    case *dwarf.PtrType:
+      if f, ok := t.Type.(*dwarf.FuncType); ok {
+        return declaration(f.ReturnType, "(*" + name + ")(" + parameterList(f) + ")")
+      }
+
+      return declaration(t.Type, "*" + name)
+    case *dwarf.QualType:
+      return t.Qual + " " + declaration(t.Type, name)
+  }
+
+  text := typeName(t)
+
+  if len(name) == 0 {
+    return text
+  }
+
+  if strings.HasPrefix(name, "*") && strings.HasSuffix(text, "*") {
+    return text + name
+  }
+
+  return text + " " + name
+}
+
+// the name of a type, without the declarators
+func typeName(t dwarf.Type) string {
+  switch t := t.(type) {
+    case nil, *dwarf.VoidType:
+      return "void"
+    case *dwarf.StructType:
+      if len(t.StructName) == 0 {
+        return t.Kind + " {...}"
+      }
+
+      return t.Kind + " " + t.StructName
+    case *dwarf.EnumType:
+      if len(t.EnumName) == 0 {
+        return "enum {...}"
+      }
+
+      return "enum " + t.EnumName
+    case *dwarf.TypedefType:
+      return t.Name
+    case *dwarf.ArrayType, *dwarf.PtrType, *dwarf.QualType:
+      return declaration(t, "")
+    case *dwarf.FuncType:
+      return declaration(t.ReturnType, "(" + parameterList(t) + ")")
+  }
+
+  if len(t.Common().Name) > 0 {
+    return t.Common().Name
+  }
+
+  return t.String()
+}
+
+func parameterList(f *dwarf.FuncType) string {
+  var params []string
+
+  for _, param := range f.ParamType {
+    if _, ok := param.(*dwarf.DotDotDotType); ok {
+      params = append(params, "...")
+    } else {
+      params = append(params, typeName(param))
+    }
+  }
+
+  if len(params) == 0 {
+    return "void"
+  }
+
+  return strings.Join(params, ", ")
+}
+
+// the type under the typedefs and the qualifiers
+func resolveType(t dwarf.Type) dwarf.Type {
+  for {
+    if typedef, ok := t.(*dwarf.TypedefType); ok {
+      t = typedef.Type
+    } else if qual, ok := t.(*dwarf.QualType); ok {
+      t = qual.Type
+    } else {
+      return t
+    }
+  }
+}
+
+func isDeclaration(entry *dwarf.Entry) bool {
+  declaration, _ := entry.Val(dwarf.AttrDeclaration).(bool)
+
+  return declaration
+}
+
+// the named types of all the compilation units, once each
+func (p *Analyzer) GetTypes() ([]TypeInfo, error) {
+  data, e := p.getDwarf()
+
+  if e != nil {
+    return nil, e
+  }
+
+  seen := map[string]bool{}
+  result := []TypeInfo{}
+
+  reader := data.Reader()
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil {
+      break
+    }
+
+    kind, found := typeKinds[entry.Tag]
+    name, _ := entry.Val(dwarf.AttrName).(string)
+
+    if found == false || len(name) == 0 || isDeclaration(entry) || seen[kind + " " + name] {
+      continue
+    }
+
+    seen[kind + " " + name] = true
+
+    info := TypeInfo{
+      Kind: kind, Name: name, Size: -1}
+
+    if t, e := data.Type(entry.Offset); e == nil {
+      info.Size = t.Size()
+
+      if typedef, ok := t.(*dwarf.TypedefType); ok {
+        info.Type = typeName(typedef.Type)
+      }
+    }
+
+    result = append(result, info)
+  }
+
+  sort.Slice(result, func(i, j int) bool {
+    if result[i].Kind == result[j].Kind {
+      return result[i].Name < result[j].Name
+    }
+
+    return result[i].Kind < result[j].Kind
+  })
+
+  return result, nil
+}
+
+func (p *Analyzer) ShowTypes() error {
+  types, e := p.GetTypes()
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(types)
+
+    return nil
+  }
+
+  for _, t := range types {
+    fmt.Printf("%-8s %-32s %6d", t.Kind, t.Name, t.Size)
+
+    if len(t.Type) > 0 {
+      fmt.Printf("  %s", t.Type)
+    }
+
+    fmt.Println()
+  }
+
+  return nil
+}
+
+// finds the entry of the name, a variable, a function or a type. The types can
+// be given with their kind, like 'struct point'
+func findEntry(data *dwarf.Data, name string) (*dwarf.Entry, error) {
+  kind := ""
+
+  for _, prefix := range []string{"struct", "union", "class", "enum"} {
+    if strings.HasPrefix(name, prefix + " ") {
+      kind, name = prefix, strings.TrimSpace(name[len(prefix):])
+    }
+  }
+
+  // the variables and functions first, then the typedefs and the base types, then the tags
+  var candidates [3]*dwarf.Entry
+
+  reader := data.Reader()
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil {
+      break
+    }
+
+    if n, _ := entry.Val(dwarf.AttrName).(string); n != name || isDeclaration(entry) {
+      continue
+    }
+
+    tag := typeKinds[entry.Tag]
+
+    if len(kind) > 0 {
+      if tag == kind && candidates[0] == nil {
+        candidates[0] = entry
+      }
+    } else if (entry.Tag == dwarf.TagVariable || entry.Tag == dwarf.TagSubprogram) && candidates[0] == nil {
+      candidates[0] = entry
+    } else if (tag == "typedef" || tag == "base") && candidates[1] == nil {
+      candidates[1] = entry
+    } else if len(tag) > 0 && candidates[2] == nil {
+      candidates[2] = entry
+    }
+  }
+
+  for _, entry := range candidates {
+    if entry != nil {
+      return entry, nil
+    }
+  }
+
+  return nil, err.TypeNotFound
+}
+
+// the dwarf information is loaded once, so that its types are the same
+// for every command
+func (p *Analyzer) getDwarf() (*dwarf.Data, error) {
+  if p.Dwarf == nil {
+    data, e := p.File.DWARF()
+
+    if e != nil {
+      return nil, err.DwarfNotFound
+    }
+
+    p.Dwarf = data
+  }
+
+  return p.Dwarf, nil
+}
+
+// the offsets in bits of the bit fields from the start of their structures.
+// They are read from the attributes of the members, the old DW_AT_bit_offset
+// counts the bits from the most significant one of the storage unit and the
+// DW_AT_data_bit_offset of dwarf 4 from the start of the structure
+func (p *Analyzer) loadBitOffsets() {
+  p.BitOffsets = map[*dwarf.StructField]int64{}
+
+  reader := p.Dwarf.Reader()
+
+  var parents []*dwarf.Entry
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil {
+      break
+    }
+
+    if entry.Tag == 0 {
+      if len(parents) > 0 {
+        parents = parents[:len(parents) - 1]
+      }
+
+      continue
+    }
+
+    var parent *dwarf.Entry
+
+    if len(parents) > 0 {
+      parent = parents[len(parents) - 1]
+    }
+
+    if entry.Children {
+      parents = append(parents, entry)
+    }
+
+    bitSize, ok := entry.Val(dwarf.AttrBitSize).(int64)
+
+    if entry.Tag != dwarf.TagMember || ok == false || parent == nil {
+      continue
+    }
+
+    t, e := p.Dwarf.Type(parent.Offset)
+
+    if e != nil {
+      continue
+    }
+
+    s, ok := t.(*dwarf.StructType)
+
+    if ok == false {
+      continue
+    }
+
+    name, _ := entry.Val(dwarf.AttrName).(string)
+
+    for _, field := range s.Field {
+      if field.Name != name || field.BitSize != bitSize {
+        continue
+      }
+
+      if offset, ok := entry.Val(dwarf.AttrDataBitOffset).(int64); ok {
+        p.BitOffsets[field] = offset
+      } else if offset, ok := entry.Val(dwarf.AttrBitOffset).(int64); ok {
+        size := field.ByteSize
+
+        if size == 0 {
+          size = field.Type.Size()
+        }
+
+        p.BitOffsets[field] = field.ByteOffset*8 + size*8 - offset - bitSize
+      }
+    }
+  }
+}
+
+// the offset in bits of a field from the start of its structure
+func (p *Analyzer) fieldBitOffset(field *dwarf.StructField) int64 {
+  if p.BitOffsets == nil {
+    p.loadBitOffsets()
+  }
+
+  if offset, found := p.BitOffsets[field]; found {
+    return offset
+  }
+
+  return field.ByteOffset*8
+}
+
+func (p *Analyzer) getMembers(t *dwarf.StructType) []MemberInfo {
+  var members []MemberInfo
+
+  for _, field := range t.Field {
+    member := MemberInfo{
+      Name: field.Name, Type: declaration(field.Type, field.Name), Offset: field.ByteOffset, Size: field.Type.Size(), BitSize: field.BitSize}
+
+    if field.BitSize > 0 {
+      member.Offset = p.fieldBitOffset(field) / 8
+    }
+
+    members = append(members, member)
+  }
+
+  return members
+}
+
+func (p *Analyzer) GetTypeDetails(name string) (*TypeDetails, error) {
+  data, e := p.getDwarf()
+
+  if e != nil {
+    return nil, e
+  }
+
+  entry, e := findEntry(data, name)
+
+  if e != nil {
+    return nil, e
+  }
+
+  details := &TypeDetails{
+    Name: name}
+
+  var t dwarf.Type
+
+  if entry.Tag == dwarf.TagSubprogram {
+    details.Kind = "function"
+    details.Type = "void"
+
+    if offset, ok := entry.Val(dwarf.AttrType).(dwarf.Offset); ok {
+      if result, e := data.Type(offset); e == nil {
+        details.Type = typeName(result)
+      }
+    }
+
+    details.Params, details.Variadic = getParameters(data, entry)
+
+    return details, nil
+  } else if entry.Tag == dwarf.TagVariable {
+    details.Kind = "variable"
+
+    offset, _ := entry.Val(dwarf.AttrType).(dwarf.Offset)
+
+    if t, e = data.Type(offset); e != nil {
+      return nil, e
+    }
+
+    details.Type = declaration(t, name)
+  } else {
+    details.Kind = typeKinds[entry.Tag]
+
+    if t, e = data.Type(entry.Offset); e != nil {
+      return nil, e
+    }
+
+    details.Name = typeName(t)
+  }
+
+  details.Size = t.Size()
+
+  for {
+    if typedef, ok := t.(*dwarf.TypedefType); ok {
+      details.Typedefs = append(details.Typedefs, typedef.Name)
+      t = typedef.Type
+    } else if qual, ok := t.(*dwarf.QualType); ok {
+      t = qual.Type
+    } else {
+      break
+    }
+  }
+
+  if len(details.Typedefs) > 0 {
+    details.Typedefs = append(details.Typedefs, typeName(t))
+  }
+
+  if s, ok := t.(*dwarf.StructType); ok {
+    details.Members = p.getMembers(s)
+  } else if enum, ok := t.(*dwarf.EnumType); ok {
+    for _, value := range enum.Val {
+      details.Values = append(details.Values, EnumValue{value.Name, value.Val})
+    }
+  }
+
+  return details, nil
+}
+
+// the parameters of a function, from the children of its entry
+func getParameters(data *dwarf.Data, function *dwarf.Entry) ([]MemberInfo, bool) {
+  var params []MemberInfo
+
+  variadic := false
+
+  if function.Children == false {
+    return params, variadic
+  }
+
+  reader := data.Reader()
+  reader.Seek(function.Offset)
+  reader.Next()
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil || entry.Tag == 0 {
+      break
+    }
+
+    if entry.Tag == dwarf.TagFormalParameter {
+      name, _ := entry.Val(dwarf.AttrName).(string)
+      offset, _ := entry.Val(dwarf.AttrType).(dwarf.Offset)
+
+      param := MemberInfo{
+        Name: name, Type: "?"}
+
+      if t, e := data.Type(offset); e == nil {
+        param.Type = declaration(t, name)
+        param.Size = t.Size()
+      }
+
+      params = append(params, param)
+    } else if entry.Tag == dwarf.TagUnspecifiedParameters {
+      variadic = true
+    }
+
+    if entry.Children {
+      reader.SkipChildren()
+    }
+  }
+
+  return params, variadic
+}
+
+// ptype <name>, the layout of a type, the signature of a function or the type
+// of a variable
+func (p *Analyzer) ShowTypeDetails(name string) error {
+  details, e := p.GetTypeDetails(name)
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(details)
+
+    return nil
+  }
+
+  if details.Kind == "function" {
+    var params []string
+
+    for _, param := range details.Params {
+      params = append(params, param.Type)
+    }
+
+    if details.Variadic {
+      params = append(params, "...")
+    }
+
+    fmt.Printf("%s %s(%s)\n", details.Type, details.Name, strings.Join(params, ", "))
+
+    return nil
+  }
+
+  // the name of the resolved type
+  header := details.Name
+
+  if details.Kind == "variable" {
+    fmt.Printf("%s /* %d bytes */\n", details.Type, details.Size)
+  }
+
+  if len(details.Typedefs) > 0 {
+    header = details.Typedefs[len(details.Typedefs) - 1]
+
+    fmt.Printf("typedef %s", strings.Join(details.Typedefs, " -> "))
+
+    if details.Members == nil && details.Values == nil {
+      fmt.Printf(" /* %d bytes */", details.Size)
+    }
+
+    fmt.Println()
+  }
+
+  if details.Members != nil {
+    fmt.Println(header + " {")
+
+    for _, member := range details.Members {
+      text := member.Type
+
+      if member.BitSize > 0 {
+        text = fmt.Sprintf("%s : %d", text, member.BitSize)
+      }
+
+      fmt.Printf("  /* 0x%04x %4d */ %s;\n", member.Offset, member.Size, text)
+    }
+
+    fmt.Printf("} /* %d bytes */\n", details.Size)
+  } else if details.Values != nil {
+    fmt.Println(header + " {")
+
+    for i, value := range details.Values {
+      separator := ","
+
+      if i == len(details.Values) - 1 {
+        separator = ""
+      }
+
+      fmt.Printf("  %s = %d%s\n", value.Name, value.Value, separator)
+    }
+
+    fmt.Printf("} /* %d bytes */\n", details.Size)
+  } else if details.Kind != "variable" && len(details.Typedefs) == 0 {
+    fmt.Printf("%s /* %d bytes */\n", details.Name, details.Size)
+  }
+
+  return nil
+}
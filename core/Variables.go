@@ -0,0 +1,503 @@
+package core
+
+import (
+  "debug/dwarf"
+  "debug/elf"
+  "encoding/binary"
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+
+  "jelf/core/debug"
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+type VariableInfo struct {
+  Name string `json:"name"`
+  Kind string `json:"kind"` // param or local
+  Type string `json:"type"`
+  Location string `json:"location"`
+  Address uint64 `json:"address,omitempty"`
+  Value string `json:"value,omitempty"`
+
+  declaration string
+}
+
+type FunctionVariables struct {
+  Function string `json:"function"`
+  Address uint64 `json:"address"`
+  Variables []VariableInfo `json:"variables"`
+}
+
+// an entry with its children, the tree of a compilation unit
+type dwarfNode struct {
+  entry *dwarf.Entry
+  children []*dwarfNode
+}
+
+func readChildren(reader *dwarf.Reader) []*dwarfNode {
+  var nodes []*dwarfNode
+
+  for {
+    entry, e := reader.Next()
+
+    if e != nil || entry == nil || entry.Tag == 0 {
+      break
+    }
+
+    node := &dwarfNode{
+      entry: entry}
+
+    if entry.Children {
+      node.children = readChildren(reader)
+    }
+
+    nodes = append(nodes, node)
+  }
+
+  return nodes
+}
+
+func containsPC(data *dwarf.Data, entry *dwarf.Entry, pc uint64) bool {
+  ranges, e := data.Ranges(entry)
+
+  if e != nil {
+    return false
+  }
+
+  for _, r := range ranges {
+    if pc >= r[0] && pc < r[1] {
+      return true
+    }
+  }
+
+  return false
+}
+
+// the function of the file address, in the tree of its compilation unit
+func findFunction(data *dwarf.Data, pc uint64) (*dwarfNode, error) {
+  reader := data.Reader()
+
+  unit, e := reader.SeekPC(pc)
+
+  if e != nil || unit.Children == false {
+    return nil, err.FunctionNotFound
+  }
+
+  nodes := readChildren(reader)
+
+  for len(nodes) > 0 {
+    var next []*dwarfNode
+
+    for _, node := range nodes {
+      if node.entry.Tag == dwarf.TagSubprogram && containsPC(data, node.entry, pc) {
+        return node, nil
+      }
+
+      // the functions of the namespaces
+      if node.entry.Tag == dwarf.TagNamespace {
+        next = append(next, node.children...)
+      }
+    }
+
+    nodes = next
+  }
+
+  return nil, err.FunctionNotFound
+}
+
+// the parameters and variables of the function, with the ones of the blocks
+// containing the address
+func collectVariables(data *dwarf.Data, node *dwarfNode, pc uint64) []*dwarf.Entry {
+  var result []*dwarf.Entry
+
+  for _, child := range node.children {
+    if child.entry.Tag == dwarf.TagFormalParameter || child.entry.Tag == dwarf.TagVariable {
+      result = append(result, child.entry)
+    } else if child.entry.Tag == dwarf.TagLexDwarfBlock && containsPC(data, child.entry, pc) {
+      result = append(result, collectVariables(data, child, pc)...)
+    }
+  }
+
+  return result
+}
+
+// a short text of the usual location expressions
+func describeLocation(expr []byte, size uint64) string {
+  if len(expr) == 0 {
+    return "optimized out"
+  }
+
+  op := expr[0]
+
+  if op >= 0x50 && op <= 0x6f {
+    if name, e := debug.GetDwarfRegister(uint64(op - 0x50), size); e == nil {
+      return "register " + name
+    }
+  } else if op >= 0x70 && op <= 0x8f {
+    if name, e := debug.GetDwarfRegister(uint64(op - 0x70), size); e == nil {
+      return fmt.Sprintf("%s%+d", name, readSleb(expr[1:]))
+    }
+  } else if op == 0x91 {
+    return fmt.Sprintf("frame base%+d", readSleb(expr[1:]))
+  } else if op == 0x03 && uint64(len(expr)) >= 1 + size {
+    if size == 4 {
+      return fmt.Sprintf("static 0x%x", binary.LittleEndian.Uint32(expr[1:]))
+    }
+
+    return fmt.Sprintf("static 0x%x", binary.LittleEndian.Uint64(expr[1:]))
+  }
+
+  return "expression " + fmt.Sprintf("%x", expr)
+}
+
+func readSleb(data []byte) int64 {
+  var result int64
+  var shift uint
+
+  for _, b := range data {
+    result = result | int64(b & 0x7f) << shift
+    shift = shift + 7
+
+    if b & 0x80 == 0 {
+      if shift < 64 && b & 0x40 != 0 {
+        result = result | -(int64(1) << shift)
+      }
+
+      break
+    }
+  }
+
+  return result
+}
+
+// locals, the parameters and variables of the function of the current address.
+// With a process or a core, their values are read in the first frame
+func (p *Analyzer) GetLocals() (*FunctionVariables, error) {
+  data, e := p.getDwarf()
+
+  if e != nil {
+    return nil, e
+  }
+
+  running := p.debugger.Running || p.IsCore()
+
+  size := uint64(8)
+
+  if p.File.Class == elf.ELFCLASS32 {
+    size = 4
+  }
+
+  context := &debug.LocationContext{
+    Size: size}
+
+  pc := p.ToFileAddress(p.Address)
+
+  if running {
+    regs, e := p.GetRegisters()
+
+    if e != nil {
+      return nil, e
+    }
+
+    _, bias := p.getLibraries()
+
+    context.Registers = regs
+    context.Memory = p.getMemory()
+    context.Bias = bias
+
+    pc = *debug.GetRegisterField(&regs, "rip") - bias
+
+    if frames, e := p.getFrames(); e == nil && len(frames) > 0 {
+      context.Cfa = frames[0].Cfa
+    }
+  }
+
+  function, e := findFunction(data, pc)
+
+  if e != nil {
+    return nil, e
+  }
+
+  name, _ := function.entry.Val(dwarf.AttrName).(string)
+
+  result := &FunctionVariables{
+    Function: name, Address: p.ViewAddress(pc), Variables: []VariableInfo{}}
+
+  if running {
+    if base, ok := function.entry.Val(dwarf.AttrFrameBase).([]byte); ok {
+      if location, e := debug.EvaluateLocation(base, context); e == nil && location.Kind == debug.LocationRegister {
+        context.FrameBase, _ = p.GetRegister(location.Register)
+      } else if e == nil {
+        context.FrameBase = location.Address
+      }
+    }
+  }
+
+  for _, entry := range collectVariables(data, function, pc) {
+    variable := VariableInfo{
+      Kind: "local", Type: "?"}
+
+    variable.Name, _ = entry.Val(dwarf.AttrName).(string)
+
+    if entry.Tag == dwarf.TagFormalParameter {
+      variable.Kind = "param"
+    }
+
+    var t dwarf.Type
+
+    if offset, ok := entry.Val(dwarf.AttrType).(dwarf.Offset); ok {
+      if t, e = data.Type(offset); e == nil {
+        variable.Type = typeName(t)
+      }
+    }
+
+    variable.declaration = variable.Type + " " + variable.Name
+
+    if t != nil {
+      variable.declaration = declaration(t, variable.Name)
+    }
+
+    field := entry.AttrField(dwarf.AttrLocation)
+
+    var expr []byte
+
+    if field == nil {
+      variable.Location = "optimized out"
+    } else if field.Class != dwarf.ClassExprLoc {
+      variable.Location = "location list"
+    } else {
+      expr, _ = field.Val.([]byte)
+      variable.Location = describeLocation(expr, size)
+    }
+
+    if running && expr != nil && t != nil {
+      variable.Address, variable.Value = p.getVariableValue(expr, context, t)
+    }
+
+    result.Variables = append(result.Variables, variable)
+  }
+
+  return result, nil
+}
+
+func (p *Analyzer) getVariableValue(expr []byte, context *debug.LocationContext, t dwarf.Type) (uint64, string) {
+  location, e := debug.EvaluateLocation(expr, context)
+
+  if e != nil {
+    return 0, "<" + e.Error() + ">"
+  }
+
+  length := t.Size()
+
+  if length < 0 {
+    return location.Address, "?"
+  }
+
+  var value []byte
+
+  if location.Kind == debug.LocationMemory {
+    if value, e = context.Memory.ReadMemory(location.Address, uint64(length)); e != nil || int64(len(value)) < length {
+      return location.Address, "<not readable>"
+    }
+
+    return location.Address, p.formatValue(t, value, 0)
+  }
+
+  word := location.Value
+
+  if location.Kind == debug.LocationRegister {
+    word = *debug.GetRegisterField(&context.Registers, location.Register)
+  }
+
+  value = make([]byte, 8)
+  binary.LittleEndian.PutUint64(value, word)
+
+  if length < 8 {
+    value = value[:length]
+  }
+
+  return 0, p.formatValue(t, value, 0)
+}
+
+func readUnsigned(data []byte) uint64 {
+  var value uint64
+
+  for i := len(data) - 1; i >= 0; i-- {
+    value = value << 8 | uint64(data[i])
+  }
+
+  return value
+}
+
+func readSigned(data []byte) int64 {
+  if len(data) == 0 || len(data) >= 8 {
+    return int64(readUnsigned(data))
+  }
+
+  shift := uint(64 - 8*len(data))
+
+  return int64(readUnsigned(data) << shift) >> shift
+}
+
+func isCharType(t dwarf.Type) bool {
+  switch resolveType(t).(type) {
+    case *dwarf.CharType, *dwarf.UcharType:
+      return true
+  }
+
+  return false
+}
+
+// the text of a c string, up to the first null
+func quoteString(data []byte) string {
+  if i := strings.IndexByte(string(data), 0); i >= 0 {
+    data = data[:i]
+  }
+
+  return strconv.Quote(string(data))
+}
+
+// formats the bytes of a value of the type, the structures and the arrays are
+// shown up to a few levels
+func (p *Analyzer) formatValue(t dwarf.Type, data []byte, depth int) string {
+  t = resolveType(t)
+
+  switch t := t.(type) {
+    case *dwarf.BoolType:
+      return strconv.FormatBool(readUnsigned(data) != 0)
+    case *dwarf.CharType:
+      return fmt.Sprintf("%d %s", readSigned(data), strconv.QuoteRune(rune(data[0])))
+    case *dwarf.UcharType:
+      return fmt.Sprintf("%d %s", readUnsigned(data), strconv.QuoteRune(rune(data[0])))
+    case *dwarf.IntType:
+      return strconv.FormatInt(readSigned(data), 10)
+    case *dwarf.UintType:
+      return strconv.FormatUint(readUnsigned(data), 10)
+    case *dwarf.FloatType:
+      if len(data) == 4 {
+        return strconv.FormatFloat(float64(math.Float32frombits(uint32(readUnsigned(data)))), 'g', -1, 32)
+      } else if len(data) == 8 {
+        return strconv.FormatFloat(math.Float64frombits(readUnsigned(data)), 'g', -1, 64)
+      }
+    case *dwarf.EnumType:
+      value := readSigned(data)
+
+      for _, v := range t.Val {
+        if v.Val == value {
+          return v.Name
+        }
+      }
+
+      return strconv.FormatInt(value, 10)
+    case *dwarf.PtrType:
+      addr := readUnsigned(data)
+      text := fmt.Sprintf("0x%x", addr)
+
+      if addr == 0 {
+        return text
+      }
+
+      if _, ok := t.Type.(*dwarf.FuncType); ok {
+        return text + p.GetProcessLocation(addr)
+      }
+
+      if isCharType(t.Type) {
+        if str, e := p.getMemory().ReadMemory(addr, 64); e == nil {
+          return text + " " + quoteString(str)
+        }
+      }
+
+      return text
+    case *dwarf.StructType:
+      if depth > 2 {
+        return "{...}"
+      }
+
+      var fields []string
+
+      for _, field := range t.Field {
+        size := field.Type.Size()
+        offset := field.ByteOffset
+
+        if field.BitSize > 0 {
+          bit := p.fieldBitOffset(field)
+          start, end := bit / 8, (bit + field.BitSize + 7) / 8
+
+          if end > int64(len(data)) {
+            continue
+          }
+
+          value := readUnsigned(data[start:end]) >> uint(bit % 8) & (1 << uint(field.BitSize) - 1)
+
+          fields = append(fields, fmt.Sprintf("%s = %d", field.Name, value))
+
+          continue
+        }
+
+        if size < 0 || offset + size > int64(len(data)) {
+          continue
+        }
+
+        fields = append(fields, field.Name + " = " + p.formatValue(field.Type, data[offset:offset + size], depth + 1))
+      }
+
+      return "{" + strings.Join(fields, ", ") + "}"
+    case *dwarf.ArrayType:
+      if isCharType(t.Type) {
+        return quoteString(data)
+      }
+
+      size := t.Type.Size()
+
+      if size <= 0 || depth > 2 {
+        return "{...}"
+      }
+
+      var elements []string
+
+      for i := int64(0); (i + 1)*size <= int64(len(data)); i++ {
+        if i == 16 {
+          elements = append(elements, "...")
+
+          break
+        }
+
+        elements = append(elements, p.formatValue(t.Type, data[i*size:(i + 1)*size], depth + 1))
+      }
+
+      return "{" + strings.Join(elements, ", ") + "}"
+  }
+
+  return fmt.Sprintf("%x", data)
+}
+
+func (p *Analyzer) ShowLocals() error {
+  result, e := p.GetLocals()
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(result)
+
+    return nil
+  }
+
+  fmt.Printf("%s at 0x%x\n", result.Function, result.Address)
+
+  for _, variable := range result.Variables {
+    if len(variable.Value) > 0 {
+      fmt.Printf("  %-5s %s = %s\n", variable.Kind, variable.declaration, variable.Value)
+    } else {
+      fmt.Printf("  %-5s %s, %s\n", variable.Kind, variable.declaration, variable.Location)
+    }
+  }
+
+  return nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+  "fmt"
+  "strconv"
+
+  "jelf/core/err"
+  "jelf/core/info"
+  "jelf/core/misc"
+)
+
+func (p *Analyzer) ShowWatchpoints() {
+  if p.Output == "json" {
+    misc.ShowJson(p.debugger.Watchpoints)
+
+    return
+  }
+
+  for _, watchpoint := range p.debugger.Watchpoints {
+    fmt.Printf("%-4d 0x%016x%s, %d bytes %s, hits %d\n", watchpoint.Id, p.ViewAddress(watchpoint.Address), p.GetLocation(watchpoint.Address),
+      watchpoint.Length, watchpoint.Kind, watchpoint.Hits)
+  }
+}
+
+// the size of the object of the symbol when the cpu can watch it, or else the
+// largest range allowed by the alignment of the address
+func (p *Analyzer) GetWatchLength(text string, addr uint64) uint64 {
+  for _, symbol := range p.Symbols {
+    if symbol.Name == text && (symbol.Size == 1 || symbol.Size == 2 || symbol.Size == 4 || symbol.Size == 8) {
+      return symbol.Size
+    }
+  }
+
+  var length uint64 = 8
+
+  for addr % length != 0 {
+    length = length / 2
+  }
+
+  return length
+}
+
+// watch <address/symbol> [length] [r|w|rw]
+func (p *Analyzer) AddWatchpoint(args []string) error {
+  addr, e := p.ResolveAddress(args[0])
+
+  if e != nil {
+    fmt.Println("address not found")
+
+    return e
+  }
+
+  addr = p.ToFileAddress(addr)
+
+  length := p.GetWatchLength(args[0], addr)
+  kind := "w"
+
+  for _, arg := range args[1:] {
+    if arg == "r" || arg == "w" || arg == "rw" {
+      kind = arg
+    } else if i, e := strconv.ParseUint(arg, 0, 64); e == nil {
+      length = i
+    } else {
+      fmt.Println("invalid option")
+
+      return err.InvalidOption
+    }
+  }
+
+  watchpoint, e := p.debugger.AddWatchpoint(addr, length, kind)
+
+  if e != nil {
+    fmt.Println(e)
+
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(watchpoint)
+
+    return nil
+  }
+
+  fmt.Printf("Watchpoint %d at 0x%016x%s, %d bytes %s\n", watchpoint.Id, p.ViewAddress(watchpoint.Address), p.GetLocation(watchpoint.Address), watchpoint.Length, kind)
+
+  return nil
+}
+
+// shows the values of the watchpoint of the stop and the instruction which accessed them
+func (p *Analyzer) ShowWatch(stop Stop) {
+  watchpoint := stop.Watchpoint
+
+  fmt.Printf("Watchpoint %d, 0x%016x%s\n", watchpoint.Id, p.ViewAddress(watchpoint.Address), p.GetLocation(watchpoint.Address))
+
+  if watchpoint.Old != watchpoint.Value {
+    fmt.Printf("Old value = 0x%x (%d)\n", watchpoint.Old, watchpoint.Old)
+    fmt.Printf("New value = 0x%x (%d)\n", watchpoint.Value, watchpoint.Value)
+  } else {
+    fmt.Printf("Value = 0x%x (%d)\n", watchpoint.Value, watchpoint.Value)
+  }
+
+  if stop.Instruction == 0 {
+    return
+  }
+
+  fmt.Printf("Accessed by 0x%016x%s\n", stop.Instruction, p.GetProcessLocation(stop.Instruction))
+
+  state := p.State
+  addr := p.ViewAddress(stop.Instruction - p.debugger.Bias)
+
+  // the libraries are only in the memory of the process
+  if p.debugger.GetLibraryAt(stop.Instruction) != nil {
+    state.Live = true
+    addr = stop.Instruction
+  }
+
+  info := &info.Information {
+    State: &state}
+
+  info.ShowAssemble(addr, 1, "")
+}
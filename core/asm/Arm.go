@@ -0,0 +1,55 @@
+package asm
+
+import (
+  "golang.org/x/arch/arm/armasm"
+)
+
+type ArmState int
+
+const (
+  ArmCode ArmState = iota
+  ThumbCode
+  ArmData
+)
+
+type Arm struct {
+  State func(addr uint64) ArmState
+}
+
+func (p *Arm) Decode(data []byte, addr uint64) (Instruction, error) {
+  state := ArmCode
+
+  if p.State != nil {
+    state = p.State(addr)
+  }
+
+  if state == ThumbCode {
+    return decodeThumb(data, addr)
+  }
+
+  if state == ArmData {
+    return dataInstruction(data, addr, 4), nil
+  }
+
+  ins, err := armasm.Decode(data, armasm.ModeARM)
+
+  if err != nil {
+    return badInstruction(data, addr, 4), err
+  }
+
+  result := Instruction{
+    Address: addr, Bytes: data[:ins.Len], Text: armasm.GNUSyntax(ins)}
+
+  // the pc reads as the current instruction plus 8 in arm state
+  for _, arg := range ins.Args {
+    if rel, ok := arg.(armasm.PCRel); ok {
+      result.Target = uint64(int64(addr) + 8 + int64(rel))
+      result.HasTarget = true
+    } else if mem, ok := arg.(armasm.Mem); ok && mem.Base == armasm.PC && mem.Mode == armasm.AddrOffset && mem.Sign == 0 {
+      result.Target = uint64(int64(addr) + 8 + int64(mem.Offset))
+      result.HasTarget = true
+    }
+  }
+
+  return result, nil
+}
@@ -0,0 +1,77 @@
+package asm
+
+import (
+  "strconv"
+  "strings"
+
+  "golang.org/x/arch/arm64/arm64asm"
+)
+
+type Arm64 struct {
+  pages map[arm64asm.Reg]uint64
+}
+
+func (p *Arm64) Decode(data []byte, addr uint64) (Instruction, error) {
+  if p.pages == nil {
+    p.pages = make(map[arm64asm.Reg]uint64)
+  }
+
+  ins, err := arm64asm.Decode(data)
+
+  if err != nil {
+    return badInstruction(data, addr, 4), err
+  }
+
+  result := Instruction{
+    Address: addr, Bytes: data[:4], Text: arm64asm.GNUSyntax(ins)}
+
+  if ins.Op == arm64asm.ADRP {
+    reg, _ := ins.Args[0].(arm64asm.Reg)
+    rel, _ := ins.Args[1].(arm64asm.PCRel)
+
+    result.Target = (addr &^ 0xfff) + uint64(rel)
+    result.HasTarget = true
+
+    p.pages[reg] = result.Target
+
+    return result, nil
+  }
+
+  // adrp loads the page and the following add gives the offset inside it
+  if ins.Op == arm64asm.ADD {
+    dst, ok1 := ins.Args[0].(arm64asm.RegSP)
+    src, ok2 := ins.Args[1].(arm64asm.RegSP)
+    imm, ok3 := ins.Args[2].(arm64asm.ImmShift)
+
+    if ok1 && ok2 && ok3 {
+      page, found := p.pages[arm64asm.Reg(src)]
+
+      if found {
+        fields := strings.Fields(strings.Replace(strings.TrimPrefix(imm.String(), "#"), ",", "", -1))
+        offset, err := strconv.ParseUint(fields[0], 0, 64)
+
+        if err == nil && len(fields) == 1 {
+          result.Target = page + offset
+          result.HasTarget = true
+        }
+      }
+
+      delete(p.pages, arm64asm.Reg(dst))
+
+      return result, nil
+    }
+  }
+
+  for _, arg := range ins.Args {
+    if rel, ok := arg.(arm64asm.PCRel); ok {
+      result.Target = uint64(int64(addr) + int64(rel))
+      result.HasTarget = true
+    }
+  }
+
+  if reg, ok := ins.Args[0].(arm64asm.Reg); ok {
+    delete(p.pages, reg)
+  }
+
+  return result, nil
+}
@@ -0,0 +1,46 @@
+package asm
+
+import (
+  "fmt"
+)
+
+type Instruction struct {
+  Address uint64
+  Bytes []byte
+  Text string
+  Target uint64
+  HasTarget bool
+}
+
+type Disassembler interface {
+  Decode(data []byte, addr uint64) (Instruction, error)
+}
+
+func badInstruction(data []byte, addr uint64, size int) Instruction {
+  if size > len(data) {
+    size = len(data)
+  }
+
+  return Instruction{
+    Address: addr, Bytes: data[:size], Text: "(bad)"}
+}
+
+func dataInstruction(data []byte, addr uint64, size int) Instruction {
+  if size > len(data) {
+    return badInstruction(data, addr, len(data))
+  }
+
+  var value uint64
+
+  for i:=size-1; i>=0; i-- {
+    value = value << 8 | uint64(data[i])
+  }
+
+  if size == 2 {
+    return Instruction{
+      Address: addr, Bytes: data[:size], Text: fmt.Sprintf(".short 0x%04x", value)}
+  }
+
+  return Instruction{
+    Address: addr, Bytes: data[:size], Text: fmt.Sprintf(".word 0x%08x", value)}
+}
@@ -0,0 +1,297 @@
+package asm
+
+import (
+  "fmt"
+  "strings"
+
+  "jelf/core/err"
+)
+
+// golang.org/x/arch/arm/armasm does not decode thumb, so the common 16 bits
+// encodings and the 32 bits branches are handled here; any other thumb-2
+// instruction is shown as raw data.
+
+var thumbConditions = []string{
+  "eq", "ne", "cs", "cc", "mi", "pl", "vs", "vc", "hi", "ls", "ge", "lt", "gt", "le", "", ""}
+
+var thumbDataProcessing = []string{
+  "ands", "eors", "lsls", "lsrs", "asrs", "adcs", "sbcs", "rors", "tst", "rsbs", "cmp", "cmn", "orrs", "muls", "bics", "mvns"}
+
+func thumbRegister(r uint16) string {
+  switch r {
+    case 13:
+      return "sp"
+    case 14:
+      return "lr"
+    case 15:
+      return "pc"
+  }
+
+  return fmt.Sprintf("r%d", r)
+}
+
+func thumbRegisterList(list uint16, extra string) string {
+  var regs []string
+
+  for i:=uint16(0); i<8; i++ {
+    if list & (1 << i) != 0 {
+      regs = append(regs, thumbRegister(i))
+    }
+  }
+
+  if len(extra) > 0 {
+    regs = append(regs, extra)
+  }
+
+  return "{" + strings.Join(regs, ", ") + "}"
+}
+
+func signExtend(value uint32, bits uint) int64 {
+  shift := 64 - bits
+
+  return int64(uint64(value) << shift) >> shift
+}
+
+func decodeThumb(data []byte, addr uint64) (Instruction, error) {
+  if len(data) < 2 {
+    return badInstruction(data, addr, len(data)), err.TruncatedInstruction
+  }
+
+  x := uint16(data[0]) | uint16(data[1]) << 8
+
+  if x >> 11 == 0x1d || x >> 11 == 0x1e || x >> 11 == 0x1f {
+    if len(data) < 4 {
+      return badInstruction(data, addr, len(data)), err.TruncatedInstruction
+    }
+
+    return decodeThumb32(data, addr, x, uint16(data[2]) | uint16(data[3]) << 8), nil
+  }
+
+  ins := Instruction{
+    Address: addr, Bytes: data[:2]}
+
+  pc := addr + 4
+  rd := x & 0x7
+  rn := (x >> 3) & 0x7
+
+  switch {
+    case x >> 13 == 0x0: // shift, add, sub, move and compare
+      op := (x >> 11) & 0x3
+      imm5 := (x >> 6) & 0x1f
+
+      if op == 0x3 {
+        rm := (x >> 6) & 0x7
+        names := []string{"adds", "subs"}
+        name := names[(x >> 9) & 0x1]
+
+        if (x >> 10) & 0x1 == 0 {
+          ins.Text = fmt.Sprintf("%s %s, %s, %s", name, thumbRegister(rd), thumbRegister(rn), thumbRegister(rm))
+        } else {
+          ins.Text = fmt.Sprintf("%s %s, %s, #%d", name, thumbRegister(rd), thumbRegister(rn), rm)
+        }
+      } else if op == 0x0 && imm5 == 0 {
+        ins.Text = fmt.Sprintf("movs %s, %s", thumbRegister(rd), thumbRegister(rn))
+      } else {
+        if op != 0x0 && imm5 == 0 {
+          imm5 = 32
+        }
+
+        ins.Text = fmt.Sprintf("%s %s, %s, #%d", []string{"lsls", "lsrs", "asrs"}[op], thumbRegister(rd), thumbRegister(rn), imm5)
+      }
+    case x >> 13 == 0x1: // move, compare, add and subtract immediate
+      name := []string{"movs", "cmp", "adds", "subs"}[(x >> 11) & 0x3]
+
+      ins.Text = fmt.Sprintf("%s %s, #%d", name, thumbRegister((x >> 8) & 0x7), x & 0xff)
+    case x >> 10 == 0x10: // data processing
+      op := (x >> 6) & 0xf
+      name := thumbDataProcessing[op]
+
+      if op == 0x9 {
+        ins.Text = fmt.Sprintf("%s %s, %s, #0", name, thumbRegister(rd), thumbRegister(rn))
+      } else if op == 0xd {
+        ins.Text = fmt.Sprintf("%s %s, %s, %s", name, thumbRegister(rd), thumbRegister(rn), thumbRegister(rd))
+      } else {
+        ins.Text = fmt.Sprintf("%s %s, %s", name, thumbRegister(rd), thumbRegister(rn))
+      }
+    case x >> 10 == 0x11: // special data and branch and exchange
+      op := (x >> 6) & 0xf
+      rdn := (x >> 4) & 0x8 | rd
+      rm := (x >> 3) & 0xf
+
+      if op >> 2 == 0x0 {
+        ins.Text = fmt.Sprintf("add %s, %s", thumbRegister(rdn), thumbRegister(rm))
+      } else if op >> 2 == 0x1 {
+        ins.Text = fmt.Sprintf("cmp %s, %s", thumbRegister(rdn), thumbRegister(rm))
+      } else if op >> 2 == 0x2 {
+        ins.Text = fmt.Sprintf("mov %s, %s", thumbRegister(rdn), thumbRegister(rm))
+      } else if op >> 1 == 0x6 {
+        ins.Text = fmt.Sprintf("bx %s", thumbRegister(rm))
+      } else {
+        ins.Text = fmt.Sprintf("blx %s", thumbRegister(rm))
+      }
+    case x >> 11 == 0x9: // load literal
+      ins.Target = (pc &^ 0x3) + uint64(x & 0xff) * 4
+      ins.HasTarget = true
+      ins.Text = fmt.Sprintf("ldr %s, [pc, #%d]", thumbRegister((x >> 8) & 0x7), (x & 0xff) * 4)
+    case x >> 12 == 0x5: // load and store with register offset
+      name := []string{"str", "strh", "strb", "ldrsb", "ldr", "ldrh", "ldrb", "ldrsh"}[(x >> 9) & 0x7]
+
+      ins.Text = fmt.Sprintf("%s %s, [%s, %s]", name, thumbRegister(rd), thumbRegister(rn), thumbRegister((x >> 6) & 0x7))
+    case x >> 13 == 0x3: // load and store with immediate offset
+      op := (x >> 11) & 0x3
+      imm5 := (x >> 6) & 0x1f
+
+      if op < 2 {
+        imm5 = imm5 * 4
+      }
+
+      ins.Text = fmt.Sprintf("%s %s, [%s, #%d]", []string{"str", "ldr", "strb", "ldrb"}[op], thumbRegister(rd), thumbRegister(rn), imm5)
+    case x >> 12 == 0x8: // load and store halfword
+      ins.Text = fmt.Sprintf("%s %s, [%s, #%d]", []string{"strh", "ldrh"}[(x >> 11) & 0x1], thumbRegister(rd), thumbRegister(rn), ((x >> 6) & 0x1f) * 2)
+    case x >> 12 == 0x9: // load and store sp relative
+      ins.Text = fmt.Sprintf("%s %s, [sp, #%d]", []string{"str", "ldr"}[(x >> 11) & 0x1], thumbRegister((x >> 8) & 0x7), (x & 0xff) * 4)
+    case x >> 11 == 0x14: // adr
+      ins.Target = (pc &^ 0x3) + uint64(x & 0xff) * 4
+      ins.HasTarget = true
+      ins.Text = fmt.Sprintf("add %s, pc, #%d", thumbRegister((x >> 8) & 0x7), (x & 0xff) * 4)
+    case x >> 11 == 0x15: // add sp plus immediate
+      ins.Text = fmt.Sprintf("add %s, sp, #%d", thumbRegister((x >> 8) & 0x7), (x & 0xff) * 4)
+    case x >> 12 == 0xb: // miscellaneous
+      ins.Text = decodeThumbMisc(x, pc, &ins)
+    case x >> 12 == 0xc: // load and store multiple
+      rn := (x >> 8) & 0x7
+
+      if (x >> 11) & 0x1 == 0 {
+        ins.Text = fmt.Sprintf("stmia %s!, %s", thumbRegister(rn), thumbRegisterList(x & 0xff, ""))
+      } else if x & (1 << rn) == 0 {
+        ins.Text = fmt.Sprintf("ldmia %s!, %s", thumbRegister(rn), thumbRegisterList(x & 0xff, ""))
+      } else {
+        ins.Text = fmt.Sprintf("ldmia %s, %s", thumbRegister(rn), thumbRegisterList(x & 0xff, ""))
+      }
+    case x >> 12 == 0xd: // conditional branch, udf and svc
+      cond := (x >> 8) & 0xf
+
+      if cond == 0xe {
+        ins.Text = fmt.Sprintf("udf #%d", x & 0xff)
+      } else if cond == 0xf {
+        ins.Text = fmt.Sprintf("svc #%d", x & 0xff)
+      } else {
+        ins.Target = uint64(int64(pc) + signExtend(uint32(x & 0xff) << 1, 9))
+        ins.HasTarget = true
+        ins.Text = fmt.Sprintf("b%s 0x%x", thumbConditions[cond], ins.Target)
+      }
+    case x >> 11 == 0x1c: // unconditional branch
+      ins.Target = uint64(int64(pc) + signExtend(uint32(x & 0x7ff) << 1, 12))
+      ins.HasTarget = true
+      ins.Text = fmt.Sprintf("b 0x%x", ins.Target)
+  }
+
+  if len(ins.Text) == 0 {
+    return dataInstruction(data, addr, 2), nil
+  }
+
+  return ins, nil
+}
+
+func decodeThumbMisc(x uint16, pc uint64, ins *Instruction) string {
+  rd := x & 0x7
+  rm := (x >> 3) & 0x7
+
+  switch {
+    case x >> 7 == 0x160:
+      return fmt.Sprintf("add sp, sp, #%d", (x & 0x7f) * 4)
+    case x >> 7 == 0x161:
+      return fmt.Sprintf("sub sp, sp, #%d", (x & 0x7f) * 4)
+    case x & 0x0500 == 0x0100: // cbz and cbnz
+      offset := ((x >> 9) & 0x1) << 6 | ((x >> 3) & 0x1f) << 1
+
+      ins.Target = pc + uint64(offset)
+      ins.HasTarget = true
+
+      return fmt.Sprintf("%s %s, 0x%x", []string{"cbz", "cbnz"}[(x >> 11) & 0x1], thumbRegister(rd), ins.Target)
+    case x >> 8 == 0xb2:
+      return fmt.Sprintf("%s %s, %s", []string{"sxth", "sxtb", "uxth", "uxtb"}[(x >> 6) & 0x3], thumbRegister(rd), thumbRegister(rm))
+    case x >> 9 == 0x5a:
+      extra := ""
+
+      if x & 0x100 != 0 {
+        extra = "lr"
+      }
+
+      return fmt.Sprintf("push %s", thumbRegisterList(x & 0xff, extra))
+    case x >> 9 == 0x5e:
+      extra := ""
+
+      if x & 0x100 != 0 {
+        extra = "pc"
+      }
+
+      return fmt.Sprintf("pop %s", thumbRegisterList(x & 0xff, extra))
+    case x >> 8 == 0xba && (x >> 6) & 0x3 != 0x2:
+      return fmt.Sprintf("%s %s, %s", []string{"rev", "rev16", "", "revsh"}[(x >> 6) & 0x3], thumbRegister(rd), thumbRegister(rm))
+    case x >> 8 == 0xbe:
+      return fmt.Sprintf("bkpt #%d", x & 0xff)
+    case x >> 8 == 0xbf:
+      if x & 0xf != 0 {
+        return fmt.Sprintf("it %s (mask 0x%x)", thumbConditions[(x >> 4) & 0xf], x & 0xf)
+      }
+
+      hints := []string{"nop", "yield", "wfe", "wfi", "sev"}
+
+      if int((x >> 4) & 0xf) < len(hints) {
+        return hints[(x >> 4) & 0xf]
+      }
+  }
+
+  return ""
+}
+
+func decodeThumb32(data []byte, addr uint64, hw1, hw2 uint16) Instruction {
+  ins := Instruction{
+    Address: addr, Bytes: data[:4]}
+
+  pc := addr + 4
+
+  if hw1 >> 11 == 0x1e && hw2 >> 15 == 0x1 {
+    s := uint32(hw1 >> 10) & 0x1
+    j1 := uint32(hw2 >> 13) & 0x1
+    j2 := uint32(hw2 >> 11) & 0x1
+    imm10 := uint32(hw1) & 0x3ff
+    imm11 := uint32(hw2) & 0x7ff
+    i1 := ^(j1 ^ s) & 0x1
+    i2 := ^(j2 ^ s) & 0x1
+    offset := signExtend(s << 24 | i1 << 23 | i2 << 22 | imm10 << 12 | imm11 << 1, 25)
+
+    switch (hw2 >> 12) & 0x5 {
+      case 0x5: // bl
+        ins.Target = uint64(int64(pc) + offset)
+        ins.HasTarget = true
+        ins.Text = fmt.Sprintf("bl 0x%x", ins.Target)
+      case 0x4: // blx, switches to arm state
+        ins.Target = uint64(int64(pc &^ 0x3) + (offset &^ 0x3))
+        ins.HasTarget = true
+        ins.Text = fmt.Sprintf("blx 0x%x", ins.Target)
+      case 0x1: // b.w
+        ins.Target = uint64(int64(pc) + offset)
+        ins.HasTarget = true
+        ins.Text = fmt.Sprintf("b.w 0x%x", ins.Target)
+      case 0x0: // conditional b.w
+        cond := (hw1 >> 6) & 0xf
+
+        if cond >> 1 != 0x7 {
+          imm6 := uint32(hw1) & 0x3f
+          offset = signExtend(s << 20 | j2 << 19 | j1 << 18 | imm6 << 12 | imm11 << 1, 21)
+
+          ins.Target = uint64(int64(pc) + offset)
+          ins.HasTarget = true
+          ins.Text = fmt.Sprintf("b%s.w 0x%x", thumbConditions[cond], ins.Target)
+        }
+    }
+  }
+
+  if len(ins.Text) == 0 {
+    ins.Text = fmt.Sprintf(".inst.w 0x%04x%04x", hw1, hw2)
+  }
+
+  return ins
+}
@@ -0,0 +1,22 @@
+package asm
+
+import (
+  "strings"
+
+  "golang.org/x/arch/x86/x86asm"
+)
+
+type X86 struct {
+  Mode int
+}
+
+func (p *X86) Decode(data []byte, addr uint64) (Instruction, error) {
+  ins, err := x86asm.Decode(data, p.Mode)
+
+  if err != nil {
+    return badInstruction(data, addr, 1), err
+  }
+
+  return Instruction{
+    Address: addr, Bytes: data[:ins.Len], Text: strings.ToLower(ins.String())}, nil
+}
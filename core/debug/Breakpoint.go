@@ -0,0 +1,184 @@
+package debug
+
+import (
+  "syscall"
+
+  "jelf/core/err"
+)
+
+// the address is the one of the file, the load bias is added when inserted. The
+// breakpoints of a shared library are moved by its base, once it is loaded
+type Breakpoint struct {
+  Id int `json:"id"`
+  Address uint64 `json:"address"`
+  Library string `json:"library,omitempty"`
+  Hits int `json:"hits"`
+  Original byte `json:"-"`
+  Inserted bool `json:"-"`
+  Location uint64 `json:"-"` // the address in the process, while inserted
+  Temporary bool `json:"-"`
+  Internal bool `json:"-"` // used by the debugger, like the one of the library loads
+}
+
+// finds the breakpoint of the address of the executable
+func (p *Debugger) GetBreakpoint(addr uint64) *Breakpoint {
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Address == addr && breakpoint.Library == "" {
+      return breakpoint
+    }
+  }
+
+  return nil
+}
+
+// finds the inserted breakpoint of the address of the process
+func (p *Debugger) GetBreakpointAt(addr uint64) *Breakpoint {
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Inserted && breakpoint.Location == addr {
+      return breakpoint
+    }
+  }
+
+  return nil
+}
+
+// the address of the breakpoint in the process
+func (p *Debugger) GetLocation(breakpoint *Breakpoint) (uint64, error) {
+  if breakpoint.Library == "" {
+    return breakpoint.Address + p.Bias, nil
+  }
+
+  for _, library := range p.Libraries {
+    if library.Name == breakpoint.Library {
+      return breakpoint.Address + library.Base, nil
+    }
+  }
+
+  return 0, err.LibraryNotLoaded
+}
+
+func (p *Debugger) GetBreakpointById(id int) *Breakpoint {
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Id == id && breakpoint.Temporary == false && breakpoint.Internal == false {
+      return breakpoint
+    }
+  }
+
+  return nil
+}
+
+// creates a breakpoint, inserted now if the process is running or when it starts
+func (p *Debugger) AddBreakpoint(addr uint64) (*Breakpoint, error) {
+  if breakpoint := p.GetBreakpoint(addr); breakpoint != nil && breakpoint.Internal == false {
+    breakpoint.Temporary = false
+
+    if breakpoint.Id == 0 {
+      breakpoint.Id = p.nextId
+      p.nextId = p.nextId + 1
+    }
+
+    return breakpoint, nil
+  }
+
+  breakpoint := &Breakpoint{
+    Id: p.nextId, Address: addr}
+
+  if p.Running {
+    if e := p.Insert(breakpoint); e != nil {
+      return nil, e
+    }
+  }
+
+  p.nextId = p.nextId + 1
+  p.Breakpoints = append(p.Breakpoints, breakpoint)
+
+  return breakpoint, nil
+}
+
+// creates a breakpoint at an address of the process, in the executable or in a library
+func (p *Debugger) AddBreakpointAt(addr uint64) (*Breakpoint, error) {
+  library := p.GetLibraryAt(addr)
+
+  if library == nil {
+    return p.AddBreakpoint(addr - p.Bias)
+  }
+
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Library == library.Name && breakpoint.Address == addr - library.Base && breakpoint.Internal == false {
+      breakpoint.Temporary = false
+
+      return breakpoint, nil
+    }
+  }
+
+  breakpoint := &Breakpoint{
+    Id: p.nextId, Address: addr - library.Base, Library: library.Name}
+
+  if e := p.Insert(breakpoint); e != nil {
+    return nil, e
+  }
+
+  p.nextId = p.nextId + 1
+  p.Breakpoints = append(p.Breakpoints, breakpoint)
+
+  return breakpoint, nil
+}
+
+func (p *Debugger) Delete(breakpoint *Breakpoint) error {
+  for i, b := range p.Breakpoints {
+    if b == breakpoint {
+      if p.Running {
+        p.Remove(breakpoint)
+      }
+
+      p.Breakpoints = append(p.Breakpoints[:i], p.Breakpoints[i + 1:]...)
+
+      return nil
+    }
+  }
+
+  return err.BreakpointNotFound
+}
+
+func (p *Debugger) Insert(breakpoint *Breakpoint) error {
+  if breakpoint.Inserted {
+    return nil
+  }
+
+  location, e := p.GetLocation(breakpoint)
+
+  if e != nil {
+    return e
+  }
+
+  addr := uintptr(location)
+  data := []byte{0}
+
+  if _, e := syscall.PtracePeekData(p.Tid, addr, data); e != nil {
+    return e
+  }
+
+  if _, e := syscall.PtracePokeData(p.Tid, addr, []byte{0xcc}); e != nil { // int3
+    return e
+  }
+
+  breakpoint.Original = data[0]
+  breakpoint.Location = location
+  breakpoint.Inserted = true
+
+  return nil
+}
+
+func (p *Debugger) Remove(breakpoint *Breakpoint) error {
+  if breakpoint.Inserted == false {
+    return nil
+  }
+
+  if _, e := syscall.PtracePokeData(p.Tid, uintptr(breakpoint.Location), []byte{breakpoint.Original}); e != nil {
+    return e
+  }
+
+  breakpoint.Inserted = false
+
+  return nil
+}
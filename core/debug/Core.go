@@ -0,0 +1,499 @@
+package debug
+
+import (
+  "bytes"
+  "debug/elf"
+  "encoding/binary"
+  "io/ioutil"
+  "os"
+  "strings"
+  "syscall"
+
+  "jelf/core/err"
+  "jelf/core/state"
+)
+
+const (
+  ntPrFPReg = 2
+  ntPrPsInfo = 3
+  ntAuxv = 6
+  ntPrXFPReg = 0x46e62b7f // the fxsave area of i386
+  ntSigInfo = 0x53494749
+  ntFile = 0x46494c45
+  atEntry = 9
+)
+
+// a thread of the dump, with its registers when the process was stopped
+type CoreThread struct {
+  Tid int `json:"tid"`
+  Signal syscall.Signal `json:"signal"`
+  Registers syscall.PtraceRegs `json:"-"`
+  FP []byte `json:"-"` // the fxsave area, or the xsave one
+  XSave bool `json:"-"`
+}
+
+// a file mapped in the process, the offset is the one of the start in the file
+type MappedFile struct {
+  Start uint64 `json:"start"`
+  End uint64 `json:"end"`
+  Offset uint64 `json:"offset"`
+  Path string `json:"path"`
+}
+
+// the state of a process saved in an ET_CORE file: the memory of its PT_LOAD
+// segments and the notes of the threads, of the signal and of the mapped files
+type Core struct {
+  Path string
+  File *elf.File
+  Pid int
+  Name string
+  Args string
+  Signal syscall.Signal
+  Code int32
+  Fault uint64 // the address of the fault, for SIGSEGV, SIGBUS, SIGILL and SIGFPE
+  Tid int // the current thread, the one of the signal first
+  Threads []*CoreThread
+  Auxv map[uint64]uint64
+  Files []MappedFile
+  Libraries []*Library
+  Bias uint64 // the one of the executable, once loaded
+
+  data []byte
+  executable *elf.File
+  frames *FrameTable
+}
+
+func OpenCore(path string, file *elf.File) (*Core, error) {
+  if file.Type != elf.ET_CORE {
+    return nil, err.InvalidOption
+  }
+
+  data, e := ioutil.ReadFile(path)
+
+  if e != nil {
+    return nil, e
+  }
+
+  core := &Core{
+    Path: path, File: file, Auxv: map[uint64]uint64{}, data: data}
+
+  var thread *CoreThread
+
+  for _, prog := range file.Progs {
+    if prog.Type != elf.PT_NOTE || prog.Off + prog.Filesz > uint64(len(data)) {
+      continue
+    }
+
+    // the notes of a thread follow its NT_PRSTATUS
+    for _, note := range state.ParseNotes(file.ByteOrder, data[prog.Off:prog.Off + prog.Filesz], prog.Align) {
+      if note.Type == ntPrStatus && note.Name == "CORE" {
+        if thread = core.parseStatus(note.Desc); thread != nil {
+          core.Threads = append(core.Threads, thread)
+        }
+      } else if note.Type == ntPrPsInfo && note.Name == "CORE" {
+        core.parseInfo(note.Desc)
+      } else if note.Type == ntAuxv && note.Name == "CORE" {
+        core.parseAuxv(note.Desc)
+      } else if note.Type == ntSigInfo && note.Name == "CORE" {
+        core.parseSignal(note.Desc)
+      } else if note.Type == ntFile && note.Name == "CORE" {
+        core.parseFiles(note.Desc)
+      } else if thread != nil && note.Type == ntPrFPReg && file.Class == elf.ELFCLASS64 && thread.FP == nil {
+        thread.FP = note.Desc
+      } else if thread != nil && note.Type == ntPrXFPReg {
+        thread.FP = note.Desc
+      } else if thread != nil && note.Type == ntX86XState {
+        thread.FP = note.Desc
+        thread.XSave = true
+      }
+    }
+  }
+
+  if len(core.Threads) == 0 {
+    return nil, err.ThreadNotFound
+  }
+
+  core.Tid = core.Threads[0].Tid
+
+  if core.Signal == 0 {
+    core.Signal = core.Threads[0].Signal
+  }
+
+  return core, nil
+}
+
+func (p *Core) getWordSize() uint64 {
+  if p.File.Class == elf.ELFCLASS32 {
+    return 4
+  }
+
+  return 8
+}
+
+func (p *Core) readWord(data []byte) uint64 {
+  if p.getWordSize() == 4 {
+    return uint64(p.File.ByteOrder.Uint32(data))
+  }
+
+  return p.File.ByteOrder.Uint64(data)
+}
+
+// the elf_prstatus of x86-64 has the registers at 112, the one of i386 at 72
+func (p *Core) parseStatus(desc []byte) *CoreThread {
+  order := p.File.ByteOrder
+
+  if p.File.Class == elf.ELFCLASS32 {
+    if len(desc) < 72 + len(compatRegisterNames)*4 {
+      return nil
+    }
+
+    thread := &CoreThread{
+      Tid: int(order.Uint32(desc[24:])), Signal: syscall.Signal(order.Uint16(desc[12:]))}
+
+    for i, name := range compatRegisterNames {
+      *GetRegisterField(&thread.Registers, name) = uint64(order.Uint32(desc[72 + i*4:]))
+    }
+
+    thread.Registers.Orig_rax = uint64(int64(int32(thread.Registers.Orig_rax)))
+
+    return thread
+  }
+
+  if len(desc) < 112 + 27*8 {
+    return nil
+  }
+
+  thread := &CoreThread{
+    Tid: int(order.Uint32(desc[32:])), Signal: syscall.Signal(order.Uint16(desc[12:]))}
+
+  binary.Read(bytes.NewReader(desc[112:]), order, &thread.Registers)
+
+  return thread
+}
+
+// the name of the command and the start of its arguments
+func (p *Core) parseInfo(desc []byte) {
+  name, args := 40, 56
+
+  if p.File.Class == elf.ELFCLASS32 {
+    name, args = 28, 44
+  }
+
+  if len(desc) < args + 80 {
+    return
+  }
+
+  p.Pid = int(p.File.ByteOrder.Uint32(desc[name - 16:]))
+  p.Name = strings.TrimRight(string(desc[name:name + 16]), "\x00")
+  p.Args = strings.TrimSpace(strings.TrimRight(string(desc[args:args + 80]), "\x00"))
+}
+
+func (p *Core) parseAuxv(desc []byte) {
+  size := p.getWordSize()
+
+  for i := uint64(0); i + 2*size <= uint64(len(desc)); i = i + 2*size {
+    p.Auxv[p.readWord(desc[i:])] = p.readWord(desc[i + size:])
+  }
+}
+
+// the siginfo of the signal which killed the process, the address follows the
+// three integers, aligned to the word
+func (p *Core) parseSignal(desc []byte) {
+  size := p.getWordSize()
+
+  if uint64(len(desc)) < 12 + 2*size {
+    return
+  }
+
+  order := p.File.ByteOrder
+
+  p.Signal = syscall.Signal(order.Uint32(desc[0:]))
+  p.Code = int32(order.Uint32(desc[8:]))
+
+  if p.HasFault() {
+    p.Fault = p.readWord(desc[(12 + size - 1) &^ (size - 1):])
+  }
+}
+
+// the signals of a fault carry the address which caused it
+func (p *Core) HasFault() bool {
+  return p.Signal == syscall.SIGSEGV || p.Signal == syscall.SIGBUS || p.Signal == syscall.SIGILL || p.Signal == syscall.SIGFPE
+}
+
+// count and page size, the ranges of the mappings and then their paths
+func (p *Core) parseFiles(desc []byte) {
+  size := p.getWordSize()
+
+  if uint64(len(desc)) < 2*size {
+    return
+  }
+
+  count := p.readWord(desc)
+  page := p.readWord(desc[size:])
+
+  offset := 2*size
+
+  // the triples follow the header of two words
+  if count > (uint64(len(desc)) - offset) / (3*size) {
+    return
+  }
+
+  names := strings.Split(string(desc[offset + count*3*size:]), "\x00")
+
+  for i := uint64(0); i < count && i < uint64(len(names)); i++ {
+    entry := desc[offset + i*3*size:]
+
+    p.Files = append(p.Files, MappedFile{
+      Start: p.readWord(entry), End: p.readWord(entry[size:]), Offset: p.readWord(entry[2*size:]) * page, Path: names[i]})
+  }
+}
+
+// the path of the mapping of the entry point, which is the one of the executable
+func (p *Core) GetExecutable() string {
+  entry, found := p.Auxv[atEntry]
+
+  for _, file := range p.Files {
+    if found && entry >= file.Start && entry < file.End {
+      return file.Path
+    }
+  }
+
+  // the command name is truncated to 15 characters
+  for _, file := range p.Files {
+    if len(p.Name) > 0 && strings.HasPrefix(file.Path[strings.LastIndex(file.Path, "/") + 1:], p.Name) {
+      return file.Path
+    }
+  }
+
+  return ""
+}
+
+// rebases the executable from its entry point and loads the symbols of the
+// other mapped elf files, the libraries. The file is nil when not found
+func (p *Core) SetExecutable(path string, file *elf.File) {
+  if entry, found := p.Auxv[atEntry]; found && file != nil && file.Entry != 0 {
+    p.Bias = entry - file.Entry
+  }
+
+  p.executable = file
+  p.frames = nil
+  p.Libraries = nil
+
+  for _, mapped := range p.Files {
+    if mapped.Offset != 0 || mapped.Path == path || getLibraryAt(p.Libraries, mapped.Start) != nil {
+      continue
+    }
+
+    if _, e := os.Stat(mapped.Path); e != nil || sameFile(mapped.Path, path) {
+      continue
+    }
+
+    library := OpenLibrary(mapped.Path, 0)
+
+    if library.End == 0 {
+      continue // not an elf file
+    }
+
+    // the first segment is the one mapped at the start of the file
+    base := mapped.Start - (library.Start &^ 0xfff)
+
+    library.Base, library.Start, library.End = base, library.Start + base, library.End + base
+
+    p.Libraries = append(p.Libraries, library)
+  }
+}
+
+func sameFile(a, b string) bool {
+  first, e1 := os.Stat(a)
+  second, e2 := os.Stat(b)
+
+  return e1 == nil && e2 == nil && os.SameFile(first, second)
+}
+
+// reads the saved memory, or the one of the mapped files for the segments
+// which were not dumped, like the code
+func (p *Core) ReadMemory(addr, length uint64) ([]byte, error) {
+  for _, prog := range p.File.Progs {
+    if prog.Type != elf.PT_LOAD || addr < prog.Vaddr || addr >= prog.Vaddr + prog.Memsz {
+      continue
+    }
+
+    if addr < prog.Vaddr + prog.Filesz {
+      start := prog.Off + addr - prog.Vaddr
+
+      // a truncated core keeps the bytes it has
+      if start >= uint64(len(p.data)) {
+        return nil, err.AddressNotMapped
+      }
+
+      limit := prog.Off + prog.Filesz
+
+      if limit > uint64(len(p.data)) {
+        limit = uint64(len(p.data))
+      }
+
+      if length > limit - start {
+        length = limit - start
+      }
+
+      return p.data[start:start + length], nil
+    }
+
+    for _, mapped := range p.Files {
+      if addr >= mapped.Start && addr < mapped.End {
+        if length > mapped.End - addr {
+          length = mapped.End - addr
+        }
+
+        if length > maxReadLength {
+          length = maxReadLength
+        }
+
+        return readFile(mapped.Path, mapped.Offset + addr - mapped.Start, length)
+      }
+    }
+  }
+
+  return nil, err.AddressNotMapped
+}
+
+func readFile(path string, offset, length uint64) ([]byte, error) {
+  f, e := os.Open(path)
+
+  if e != nil {
+    return nil, err.AddressNotMapped
+  }
+
+  defer f.Close()
+
+  data := make([]byte, length)
+
+  n, _ := f.ReadAt(data, int64(offset))
+
+  if n == 0 {
+    return nil, err.AddressNotMapped
+  }
+
+  return data[:n], nil
+}
+
+func (p *Core) ReadWord(addr uint64) (uint64, error) {
+  size := p.getWordSize()
+
+  data, e := p.ReadMemory(addr, size)
+
+  if e != nil || uint64(len(data)) < size {
+    return 0, err.AddressNotMapped
+  }
+
+  return p.readWord(data), nil
+}
+
+func (p *Core) GetThread(tid int) *CoreThread {
+  for _, thread := range p.Threads {
+    if thread.Tid == tid {
+      return thread
+    }
+  }
+
+  return nil
+}
+
+func (p *Core) SetThread(tid int) error {
+  if p.GetThread(tid) == nil {
+    return err.ThreadNotFound
+  }
+
+  p.Tid = tid
+
+  return nil
+}
+
+func (p *Core) GetRegisters() (syscall.PtraceRegs, error) {
+  thread := p.GetThread(p.Tid)
+
+  if thread == nil {
+    return syscall.PtraceRegs{}, err.ThreadNotFound
+  }
+
+  return thread.Registers, nil
+}
+
+func (p *Core) GetRegister(name string) (uint64, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return 0, e
+  }
+
+  full, half := resolveRegister(name)
+
+  field := GetRegisterField(&regs, full)
+
+  if field == nil {
+    return 0, err.RegisterNotFound
+  }
+
+  if half {
+    return *field & 0xffffffff, nil
+  }
+
+  return *field, nil
+}
+
+func (p *Core) GetFPRegisters() (FPRegisters, error) {
+  thread := p.GetThread(p.Tid)
+
+  if thread == nil || len(thread.FP) < 512 {
+    return FPRegisters{}, err.RegisterNotFound
+  }
+
+  return DecodeFPRegisters(thread.FP, thread.XSave), nil
+}
+
+// the saved segments, with the files mapped at their addresses
+func (p *Core) GetMaps() []Map {
+  var result []Map
+
+  for _, prog := range p.File.Progs {
+    if prog.Type != elf.PT_LOAD {
+      continue
+    }
+
+    m := Map{
+      Start: prog.Vaddr, End: prog.Vaddr + prog.Memsz, Perms: "---p"}
+
+    perms := []byte(m.Perms)
+
+    for i, flag := range []elf.ProgFlag{elf.PF_R, elf.PF_W, elf.PF_X} {
+      if prog.Flags & flag != 0 {
+        perms[i] = "rwx"[i]
+      }
+    }
+
+    m.Perms = string(perms)
+
+    for _, mapped := range p.Files {
+      if prog.Vaddr >= mapped.Start && prog.Vaddr < mapped.End {
+        m.Offset = mapped.Offset + prog.Vaddr - mapped.Start
+        m.Path = mapped.Path
+      }
+    }
+
+    result = append(result, m)
+  }
+
+  return result
+}
+
+func (p *Core) GetLibraryAt(addr uint64) *Library {
+  return getLibraryAt(p.Libraries, addr)
+}
+
+func (p *Core) GetLibrarySymbol(addr uint64) (string, uint64, error) {
+  return getLibrarySymbol(p.Libraries, addr)
+}
+
+func (p *Core) GetLibrarySymbolAddress(name string) (uint64, error) {
+  return getLibrarySymbolAddress(p.Libraries, name)
+}
@@ -0,0 +1,388 @@
+package debug
+
+import (
+  "bytes"
+  "debug/elf"
+  "encoding/binary"
+  "io/ioutil"
+  "os"
+  "strconv"
+  "strings"
+  "unsafe"
+
+  "jelf/core/err"
+)
+
+const (
+  pageSize = 0x1000
+  prStatusSize64 = 336
+  prStatusSize32 = 144
+  prPsInfoSize64 = 136
+  prPsInfoSize32 = 124
+  coreChunkSize = 0x100000
+)
+
+// a memory region of the process, the dumped ones have a size in the file
+type coreSegment struct {
+  Map Map
+  Size uint64
+}
+
+// writes the process as an ET_CORE file, like the kernel does: a PT_NOTE with
+// the threads, the process, the auxiliary vector and the mapped files, then a
+// PT_LOAD for each region of /proc/pid/maps. The memory is copied by chunks
+// from /proc/pid/mem to the file, the pages which can not be read are zeros
+func (p *Debugger) WriteCore(path string) error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  maps, e := p.GetMaps()
+
+  if e != nil {
+    return e
+  }
+
+  p.save()
+
+  notes, e := p.getCoreNotes(maps)
+
+  if e != nil {
+    return e
+  }
+
+  var segments []coreSegment
+
+  for _, m := range maps {
+    segment := coreSegment{
+      Map: m}
+
+    // the vsyscall page is not part of the address space of the process
+    if m.Perms[0] == 'r' && m.Path != "[vsyscall]" {
+      segment.Size = m.End - m.Start
+    }
+
+    segments = append(segments, segment)
+  }
+
+  var buffer bytes.Buffer
+
+  order := p.File.ByteOrder
+  is64 := p.File.Class == elf.ELFCLASS64
+
+  headerSize, progSize := uint64(52), uint64(32)
+
+  if is64 {
+    headerSize, progSize = 64, 56
+  }
+
+  count := uint64(len(segments) + 1)
+
+  // the memory starts at a page boundary after the headers and the notes
+  offset := headerSize + count*progSize
+  notesOffset := offset
+
+  offset = (offset + uint64(len(notes)) + pageSize - 1) &^ (pageSize - 1)
+  memoryOffset := offset
+
+  progs := []elf.ProgHeader{elf.ProgHeader{
+    Type: elf.PT_NOTE, Off: notesOffset, Filesz: uint64(len(notes)), Align: 4}}
+
+  for _, segment := range segments {
+    prog := elf.ProgHeader{
+      Type: elf.PT_LOAD, Off: offset, Vaddr: segment.Map.Start, Filesz: segment.Size, Memsz: segment.Map.End - segment.Map.Start, Align: pageSize}
+
+    for i, flag := range []elf.ProgFlag{elf.PF_R, elf.PF_W, elf.PF_X} {
+      if segment.Map.Perms[i] != '-' {
+        prog.Flags = prog.Flags | flag
+      }
+    }
+
+    progs = append(progs, prog)
+
+    offset = offset + prog.Filesz
+  }
+
+  ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(p.File.Class), byte(p.File.Data), byte(elf.EV_CURRENT), byte(p.File.OSABI)}
+
+  if is64 {
+    binary.Write(&buffer, order, elf.Header64{
+      Ident: ident, Type: uint16(elf.ET_CORE), Machine: uint16(p.File.Machine), Version: uint32(elf.EV_CURRENT),
+      Phoff: headerSize, Ehsize: uint16(headerSize), Phentsize: uint16(progSize), Phnum: uint16(count), Shentsize: 64})
+
+    for _, prog := range progs {
+      binary.Write(&buffer, order, elf.Prog64{
+        Type: uint32(prog.Type), Flags: uint32(prog.Flags), Off: prog.Off, Vaddr: prog.Vaddr, Filesz: prog.Filesz, Memsz: prog.Memsz, Align: prog.Align})
+    }
+  } else {
+    binary.Write(&buffer, order, elf.Header32{
+      Ident: ident, Type: uint16(elf.ET_CORE), Machine: uint16(p.File.Machine), Version: uint32(elf.EV_CURRENT),
+      Phoff: uint32(headerSize), Ehsize: uint16(headerSize), Phentsize: uint16(progSize), Phnum: uint16(count), Shentsize: 40})
+
+    for _, prog := range progs {
+      binary.Write(&buffer, order, elf.Prog32{
+        Type: uint32(prog.Type), Flags: uint32(prog.Flags), Off: uint32(prog.Off), Vaddr: uint32(prog.Vaddr), Filesz: uint32(prog.Filesz), Memsz: uint32(prog.Memsz), Align: uint32(prog.Align)})
+    }
+  }
+
+  buffer.Write(notes)
+  buffer.Write(make([]byte, memoryOffset - uint64(buffer.Len())))
+
+  f, e := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, 0600)
+
+  if e != nil {
+    return e
+  }
+
+  defer f.Close()
+
+  if _, e := f.Write(buffer.Bytes()); e != nil {
+    return e
+  }
+
+  mem, e := os.Open("/proc/" + strconv.Itoa(p.Pid) + "/mem")
+
+  if e != nil {
+    return e
+  }
+
+  defer mem.Close()
+
+  for _, segment := range segments {
+    for done := uint64(0); done < segment.Size; {
+      length := segment.Size - done
+
+      if length > coreChunkSize {
+        length = coreChunkSize
+      }
+
+      data, e := p.readRegion(mem, segment.Map.Start + done, length)
+
+      if e != nil {
+        data = make([]byte, length)
+      }
+
+      if _, e := f.Write(data); e != nil {
+        return e
+      }
+
+      done = done + length
+    }
+  }
+
+  return f.Close()
+}
+
+// reads a part of a region through /proc/pid/mem, without the int3 of the breakpoints
+func (p *Debugger) readRegion(mem *os.File, addr, length uint64) ([]byte, error) {
+  data := make([]byte, length)
+
+  if _, e := mem.ReadAt(data, int64(addr)); e != nil {
+    return nil, e
+  }
+
+  for _, breakpoint := range p.Breakpoints {
+    if at := breakpoint.Location; breakpoint.Inserted && at >= addr && at < addr + length {
+      data[at - addr] = breakpoint.Original
+    }
+  }
+
+  return data, nil
+}
+
+func appendNote(notes []byte, order binary.ByteOrder, t uint32, desc []byte) []byte {
+  header := make([]byte, 20)
+
+  order.PutUint32(header[0:], 5)
+  order.PutUint32(header[4:], uint32(len(desc)))
+  order.PutUint32(header[8:], t)
+
+  copy(header[12:], "CORE")
+
+  notes = append(notes, header...)
+  notes = append(notes, desc...)
+
+  return append(notes, make([]byte, (4 - len(desc) % 4) % 4)...)
+}
+
+// the fields of /proc/pid/stat after the name: state, ppid, pgrp and session
+func (p *Debugger) getProcessStat() []string {
+  data, _ := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/stat")
+
+  text := string(data)
+
+  if i := strings.LastIndex(text, ")"); i >= 0 {
+    if fields := strings.Fields(text[i + 1:]); len(fields) >= 4 {
+      return fields
+    }
+  }
+
+  return []string{"R", "0", "0", "0"}
+}
+
+func (p *Debugger) getCoreNotes(maps []Map) ([]byte, error) {
+  order := p.File.ByteOrder
+  is64 := p.File.Class == elf.ELFCLASS64
+  size := p.GetWordSize()
+
+  stat := p.getProcessStat()
+
+  ppid, _ := strconv.Atoi(stat[1])
+  pgrp, _ := strconv.Atoi(stat[2])
+  sid, _ := strconv.Atoi(stat[3])
+
+  var notes []byte
+
+  current := p.Tid
+
+  defer func() {
+    p.Tid = current
+  }()
+
+  // the current thread is the first one, like the one of the signal in the dumps of the kernel
+  threads := []*Thread{p.GetThread(current)}
+
+  for _, thread := range p.Threads {
+    if thread.Tid != current {
+      threads = append(threads, thread)
+    }
+  }
+
+  for _, thread := range threads {
+    if thread == nil {
+      continue
+    }
+
+    p.Tid = thread.Tid
+
+    regs, e := p.GetRegisters()
+
+    if e != nil {
+      return nil, e
+    }
+
+    // the fxsave area, the one of i386 is not given by the 64 bits ptrace
+    var fp []byte
+
+    if is64 {
+      fp = make([]byte, 512)
+
+      if p.ptrace(ptraceGetFPRegs, 0, uintptr(unsafe.Pointer(&fp[0]))) != nil {
+        fp = nil
+      }
+    }
+
+    var status []byte
+
+    if is64 {
+      status = make([]byte, prStatusSize64)
+
+      var data bytes.Buffer
+
+      binary.Write(&data, order, regs)
+
+      copy(status[112:], data.Bytes())
+    } else {
+      status = make([]byte, prStatusSize32)
+
+      for i, name := range compatRegisterNames {
+        order.PutUint32(status[72 + i*4:], uint32(*GetRegisterField(&regs, name)))
+      }
+    }
+
+    order.PutUint32(status[0:], uint32(thread.Signal))
+    order.PutUint16(status[12:], uint16(thread.Signal))
+
+    pid := 32
+
+    if is64 == false {
+      pid = 24
+    }
+
+    order.PutUint32(status[pid:], uint32(thread.Tid))
+    order.PutUint32(status[pid + 4:], uint32(ppid))
+    order.PutUint32(status[pid + 8:], uint32(pgrp))
+    order.PutUint32(status[pid + 12:], uint32(sid))
+
+    if fp != nil {
+      order.PutUint32(status[prStatusSize64 - 8:], 1) // pr_fpvalid
+    }
+
+    notes = appendNote(notes, order, ntPrStatus, status)
+
+    if fp != nil {
+      notes = appendNote(notes, order, ntPrFPReg, fp)
+    }
+
+    // the process notes follow the ones of the first thread
+    if thread.Tid == current {
+      notes = appendNote(notes, order, ntPrPsInfo, p.getProcessInfo(stat[0], ppid, pgrp, sid))
+
+      if auxv, e := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/auxv"); e == nil {
+        notes = appendNote(notes, order, ntAuxv, auxv)
+      }
+
+      notes = appendNote(notes, order, ntFile, p.getMappedFiles(maps, size))
+    }
+  }
+
+  return notes, nil
+}
+
+func (p *Debugger) getProcessInfo(state string, ppid, pgrp, sid int) []byte {
+  order := p.File.ByteOrder
+
+  name, args := 40, 56
+  info := make([]byte, prPsInfoSize64)
+
+  if p.File.Class == elf.ELFCLASS32 {
+    name, args = 28, 44
+    info = make([]byte, prPsInfoSize32)
+  }
+
+  if len(state) > 0 {
+    info[1] = state[0]
+  }
+
+  order.PutUint32(info[name - 16:], uint32(p.Pid))
+  order.PutUint32(info[name - 12:], uint32(ppid))
+  order.PutUint32(info[name - 8:], uint32(pgrp))
+  order.PutUint32(info[name - 4:], uint32(sid))
+
+  comm, _ := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/comm")
+  cmdline, _ := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/cmdline")
+
+  copy(info[name:name + 15], strings.TrimSpace(string(comm)))
+  copy(info[args:args + 79], strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1)))
+
+  return info
+}
+
+// the file backed regions, with their offsets in pages
+func (p *Debugger) getMappedFiles(maps []Map, size uint64) []byte {
+  order := p.File.ByteOrder
+
+  var words []uint64
+  var names []byte
+
+  for _, m := range maps {
+    if strings.HasPrefix(m.Path, "/") {
+      words = append(words, m.Start, m.End, m.Offset / pageSize)
+      names = append(append(names, m.Path...), 0)
+    }
+  }
+
+  words = append([]uint64{uint64(len(words) / 3), pageSize}, words...)
+
+  data := make([]byte, uint64(len(words))*size)
+
+  for i, word := range words {
+    if size == 4 {
+      order.PutUint32(data[uint64(i)*size:], uint32(word))
+    } else {
+      order.PutUint64(data[uint64(i)*size:], word)
+    }
+  }
+
+  return append(data, names...)
+}
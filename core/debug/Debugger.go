@@ -1 +1,904 @@
 package debug
+
+import (
+  "debug/elf"
+  "io"
+  "os"
+  "os/exec"
+  "path/filepath"
+  "runtime"
+  "strconv"
+  "strings"
+  "sync/atomic"
+  "syscall"
+  "time"
+
+  "golang.org/x/arch/x86/x86asm"
+
+  "jelf/core/err"
+  "jelf/core/misc"
+)
+
+const (
+  ptraceOptionExitKill = 0x100000
+  addrNoRandomize = 0x0040000
+  maxReadLength = 0x100000
+)
+
+// files of the standard streams, empty to keep the terminal
+type Redirection struct {
+  Stdin string
+  Stdout string
+  Stderr string
+  AppendStdout bool
+  AppendStderr bool
+}
+
+type Debugger struct {
+  Path string
+  File *elf.File
+  Env []string
+  Dir string
+  Tty string // a terminal device, 'pty' for a new pseudo terminal or empty to share the one of jelf
+  FollowFork string // parent or child
+  Pid int
+  Tid int // the current thread, the one of the registers and of the last stop
+  Threads []*Thread
+  Base uint64
+  Bias uint64
+  DisableRandomization bool
+  Breakpoints []*Breakpoint
+  Hit *Breakpoint
+  Watchpoints []*Watchpoint
+  Watch *Watchpoint // the watchpoint of the last stop
+  Libraries []*Library
+  Status syscall.WaitStatus
+  Signal syscall.Signal
+  SyscallStop bool
+  InSyscall bool
+  Syscall *Syscall
+  Exec bool // the last stop is the one of a new program
+  Running bool
+  Attached bool
+
+  nextId int
+  frames *FrameTable // the call frame information of the executable, once loaded
+  relay chan bool
+  forwarding int32 // set while the process runs, the input is then copied to the pty
+  early map[int]bool // new threads stopped before the event of their creation
+}
+
+func NewDebugger(path string, file *elf.File) *Debugger {
+  return &Debugger{
+    Path: path, File: file, Env: os.Environ(), DisableRandomization: true, FollowFork: "parent", nextId: 1}
+}
+
+func (p *Debugger) SetEnv(key, value string) {
+  p.UnsetEnv(key)
+
+  p.Env = append(p.Env, key + "=" + value)
+}
+
+func (p *Debugger) UnsetEnv(key string) {
+  var env []string
+
+  for _, entry := range p.Env {
+    if strings.HasPrefix(entry, key + "=") == false {
+      env = append(env, entry)
+    }
+  }
+
+  p.Env = env
+}
+
+// opens the terminal of the process, copying the output of a new pty to the one
+// of jelf, and the input of jelf to the pty while the process runs
+func (p *Debugger) openTty() (*os.File, error) {
+  if p.Tty != "pty" {
+    return os.OpenFile(p.Tty, os.O_RDWR, 0)
+  }
+
+  master, slave, e := misc.OpenPty()
+
+  if e != nil {
+    return nil, e
+  }
+
+  relay := make(chan bool)
+
+  go func() {
+    io.Copy(os.Stdout, master)
+
+    master.Close()
+
+    close(relay)
+  }()
+
+  go p.forwardInput(master, relay)
+
+  p.relay = relay
+
+  return slave, nil
+}
+
+// the standard input is polled, so that nothing is read once the process
+// stops and jelf reads its commands again
+func (p *Debugger) forwardInput(master *os.File, relay chan bool) {
+  data := make([]byte, 256)
+
+  for {
+    select {
+      case <-relay:
+        return
+      default:
+    }
+
+    if atomic.LoadInt32(&p.forwarding) == 0 {
+      time.Sleep(50 * time.Millisecond)
+
+      continue
+    }
+
+    fds := syscall.FdSet{}
+    fds.Bits[0] = 1 // the descriptor 0
+
+    timeout := syscall.Timeval{Usec: 50000}
+
+    if n, e := syscall.Select(1, &fds, nil, nil, &timeout); e != nil || n == 0 || atomic.LoadInt32(&p.forwarding) == 0 {
+      continue
+    }
+
+    n, e := syscall.Read(0, data)
+
+    if e != nil || n <= 0 {
+      return
+    }
+
+    master.Write(data[:n])
+  }
+}
+
+func (p *Debugger) Start(args []string, redirection Redirection) error {
+  if p.Running {
+    return err.AlreadyRunning
+  }
+
+  if p.File.Machine != elf.EM_X86_64 && p.File.Machine != elf.EM_386 {
+    return err.UnsupportedArchitecture
+  }
+
+  // every ptrace request must come from the thread that started the tracee
+  runtime.LockOSThread()
+
+  path := p.Path
+
+  if strings.Contains(path, "/") == false {
+    path = "./" + path
+  }
+
+  if abs, e := filepath.Abs(path); e == nil {
+    path = abs // the working directory may be changed
+  }
+
+  cmd := exec.Command(path, args...)
+
+  cmd.Env = append([]string{}, p.Env...) // a nil environment would be the one of jelf
+  cmd.Dir = p.Dir
+
+  cmd.Stdin = os.Stdin
+  cmd.Stdout = os.Stdout
+  cmd.Stderr = os.Stderr
+
+  cmd.SysProcAttr = &syscall.SysProcAttr{
+    Ptrace: true}
+
+  var files []*os.File
+
+  defer func() {
+    for _, file := range files {
+      file.Close()
+    }
+  }()
+
+  var tty *os.File
+
+  if len(p.Tty) > 0 {
+    f, e := p.openTty()
+
+    if e != nil {
+      return e
+    }
+
+    files = append(files, f)
+
+    tty = f
+
+    cmd.Stdin = tty
+    cmd.Stdout = tty
+    cmd.Stderr = tty
+  }
+
+  if len(redirection.Stdin) > 0 {
+    f, e := os.Open(redirection.Stdin)
+
+    if e != nil {
+      return e
+    }
+
+    files = append(files, f)
+
+    cmd.Stdin = f
+  }
+
+  flags := func(append bool) int {
+    if append {
+      return os.O_WRONLY | os.O_CREATE | os.O_APPEND
+    }
+
+    return os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+  }
+
+  if len(redirection.Stdout) > 0 {
+    f, e := os.OpenFile(redirection.Stdout, flags(redirection.AppendStdout), 0644)
+
+    if e != nil {
+      return e
+    }
+
+    files = append(files, f)
+
+    cmd.Stdout = f
+  }
+
+  if len(redirection.Stderr) > 0 {
+    f, e := os.OpenFile(redirection.Stderr, flags(redirection.AppendStderr), 0644)
+
+    if e != nil {
+      return e
+    }
+
+    files = append(files, f)
+
+    cmd.Stderr = f
+  }
+
+  // a new session where the terminal is the controlling one
+  if tty != nil {
+    for i, stream := range []interface{}{cmd.Stdin, cmd.Stdout, cmd.Stderr} {
+      if stream == tty {
+        cmd.SysProcAttr.Setsid = true
+        cmd.SysProcAttr.Setctty = true
+        cmd.SysProcAttr.Ctty = i
+
+        break
+      }
+    }
+  }
+
+  // the personality is inherited by the process forked from this thread
+  if p.DisableRandomization {
+    persona, _, errno := syscall.RawSyscall(syscall.SYS_PERSONALITY, 0xffffffff, 0, 0)
+
+    if errno == 0 {
+      syscall.RawSyscall(syscall.SYS_PERSONALITY, persona | addrNoRandomize, 0, 0)
+
+      defer syscall.RawSyscall(syscall.SYS_PERSONALITY, persona, 0, 0)
+    }
+  }
+
+  if e := cmd.Start(); e != nil {
+    return e
+  }
+
+  p.Pid = cmd.Process.Pid
+  p.Tid = p.Pid
+  p.Threads = []*Thread{&Thread{Tid: p.Pid}}
+  p.early = map[int]bool{}
+  p.Running = true
+
+  if e := p.Wait(); e != nil {
+    return e
+  }
+
+  syscall.PtraceSetOptions(p.Pid, ptraceOptionExitKill | ptraceOptions)
+
+  p.Base, p.Bias = p.GetLoadBase()
+
+  for _, breakpoint := range p.Breakpoints {
+    p.Insert(breakpoint)
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    p.InsertWatchpoint(watchpoint)
+  }
+
+  p.WatchLibraries()
+
+  return nil
+}
+
+// stops the threads of the process and traces them, the breakpoints are inserted in its image
+func (p *Debugger) Attach(pid int) error {
+  if p.Running {
+    return err.AlreadyRunning
+  }
+
+  if p.File.Machine != elf.EM_X86_64 && p.File.Machine != elf.EM_386 {
+    return err.UnsupportedArchitecture
+  }
+
+  runtime.LockOSThread()
+
+  if e := syscall.PtraceAttach(pid); e != nil {
+    return e
+  }
+
+  p.Pid = pid
+  p.Tid = pid
+  p.Threads = []*Thread{&Thread{Tid: pid}}
+  p.early = map[int]bool{}
+  p.Running = true
+  p.Attached = true
+
+  if e := p.Wait(); e != nil {
+    return e
+  }
+
+  p.Signal = 0 // the stop of the attach is not delivered
+
+  syscall.PtraceSetOptions(p.Pid, ptraceOptions)
+
+  p.attachThreads()
+
+  p.Base, p.Bias = p.GetLoadBase()
+
+  for _, breakpoint := range p.Breakpoints {
+    p.Insert(breakpoint)
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    p.InsertWatchpoint(watchpoint)
+  }
+
+  p.WatchLibraries()
+
+  return nil
+}
+
+// restores the original bytes of the breakpoints and lets the process go
+func (p *Debugger) Detach() error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  for _, breakpoint := range p.Breakpoints {
+    p.Remove(breakpoint)
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    p.RemoveWatchpoint(watchpoint)
+  }
+
+  p.save()
+
+  var e error
+
+  for _, thread := range p.Threads {
+    if failure := ptraceRequest(syscall.PTRACE_DETACH, thread.Tid, 0, uintptr(thread.Signal)); thread.Tid == p.Pid {
+      e = failure
+    }
+  }
+
+  p.Release()
+
+  return e
+}
+
+func (p *Debugger) Kill() error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  syscall.Kill(p.Pid, syscall.SIGKILL)
+
+  // every thread reports its exit, the one of the leader is the last
+  for p.Running {
+    var status syscall.WaitStatus
+
+    tid, e := syscall.Wait4(-1, &status, syscall.WALL, nil)
+
+    if e != nil {
+      break
+    }
+
+    p.handle(tid, status)
+  }
+
+  p.Release()
+
+  return nil
+}
+
+// forgets the state of the finished process, keeping the breakpoints for the next run
+func (p *Debugger) Release() {
+  p.Running = false
+  p.Attached = false
+  p.Hit = nil
+  p.Watch = nil
+  p.Signal = 0
+  p.InSyscall = false
+  p.Syscall = nil
+  p.Threads = nil
+  p.early = nil
+
+  // the output of the pty is shown before the exit, unless a child keeps it open
+  if p.relay != nil {
+    select {
+      case <-p.relay:
+      case <-time.After(time.Second):
+    }
+
+    p.relay = nil
+  }
+
+  var breakpoints []*Breakpoint
+
+  for _, breakpoint := range p.Breakpoints {
+    breakpoint.Inserted = false
+
+    if breakpoint.Internal == false {
+      breakpoints = append(breakpoints, breakpoint)
+    }
+  }
+
+  p.Breakpoints = breakpoints
+  p.Libraries = nil
+
+  for _, watchpoint := range p.Watchpoints {
+    watchpoint.Inserted = false
+  }
+}
+
+// waits for the next stop of the current thread
+func (p *Debugger) Wait() error {
+  var status syscall.WaitStatus
+
+  if _, e := syscall.Wait4(p.Tid, &status, syscall.WALL, nil); e != nil {
+    return e
+  }
+
+  if thread := p.GetThread(p.Tid); thread != nil {
+    thread.Stopped = status.Stopped()
+  }
+
+  p.setStatus(status)
+
+  return nil
+}
+
+func (p *Debugger) setStatus(status syscall.WaitStatus) {
+  p.Status = status
+  p.Signal = 0
+  p.SyscallStop = false
+
+  if status.Exited() || status.Signaled() {
+    p.Release()
+  } else if status.Stopped() && status.StopSignal() == syscall.SIGTRAP | 0x80 {
+    p.SyscallStop = true // PTRACE_O_TRACESYSGOOD
+  } else if status.Stopped() && status.StopSignal() != syscall.SIGTRAP {
+    p.Signal = status.StopSignal() // delivered when the process is resumed
+  } else if status.Stopped() {
+    p.checkWatchpoints()
+  }
+}
+
+// pie executables are loaded at a random base, so the addresses of the file
+// must be moved by the bias, the difference to the first mapping of the executable
+func (p *Debugger) GetLoadBase() (uint64, uint64) {
+  var lowest uint64 = ^uint64(0)
+
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_LOAD && prog.Vaddr < lowest {
+      lowest = prog.Vaddr &^ 0xfff
+    }
+  }
+
+  if lowest == ^uint64(0) {
+    lowest = 0
+  }
+
+  if p.File.Type != elf.ET_DYN {
+    return lowest, 0
+  }
+
+  exe, e := os.Readlink("/proc/" + strconv.Itoa(p.Pid) + "/exe")
+
+  if e != nil {
+    return lowest, 0
+  }
+
+  maps, e := p.GetMaps()
+
+  if e != nil {
+    return lowest, 0
+  }
+
+  for _, m := range maps {
+    if m.Path == exe && m.Offset == 0 {
+      return m.Start, m.Start - lowest
+    }
+  }
+
+  return lowest, 0
+}
+
+func (p *Debugger) GetRegisters() (syscall.PtraceRegs, error) {
+  var regs syscall.PtraceRegs
+
+  if p.Running == false {
+    return regs, err.NotRunning
+  }
+
+  if p.File.Class == elf.ELFCLASS32 {
+    return p.getCompatRegisters()
+  }
+
+  e := syscall.PtraceGetRegs(p.Tid, &regs)
+
+  return regs, e
+}
+
+func (p *Debugger) SetRegisters(regs syscall.PtraceRegs) error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  if p.File.Class == elf.ELFCLASS32 {
+    return p.setCompatRegisters(regs)
+  }
+
+  return syscall.PtraceSetRegs(p.Tid, &regs)
+}
+
+func (p *Debugger) GetPC() (uint64, error) {
+  regs, e := p.GetRegisters()
+
+  return regs.Rip, e
+}
+
+func (p *Debugger) SetPC(pc uint64) error {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return e
+  }
+
+  regs.Rip = pc
+
+  return p.SetRegisters(regs)
+}
+
+// reads the memory of the process, hiding the int3 of the breakpoints. The
+// reads are done word by word, they are limited to maxReadLength bytes
+func (p *Debugger) ReadMemory(addr, length uint64) ([]byte, error) {
+  if p.Running == false {
+    return nil, err.NotRunning
+  }
+
+  if length > maxReadLength {
+    length = maxReadLength
+  }
+
+  data := make([]byte, length)
+
+  n, e := syscall.PtracePeekData(p.Tid, uintptr(addr), data)
+
+  if n == 0 && e != nil {
+    return nil, e
+  }
+
+  data = data[:n]
+
+  for _, breakpoint := range p.Breakpoints {
+    if at := breakpoint.Location; breakpoint.Inserted && at >= addr && at < addr + uint64(len(data)) {
+      data[at - addr] = breakpoint.Original
+    }
+  }
+
+  return data, nil
+}
+
+// writes the memory of the process, keeping the int3 of the breakpoints
+func (p *Debugger) WriteMemory(addr uint64, data []byte) error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  data = append([]byte{}, data...)
+
+  for _, breakpoint := range p.Breakpoints {
+    if at := breakpoint.Location; breakpoint.Inserted && at >= addr && at < addr + uint64(len(data)) {
+      breakpoint.Original = data[at - addr]
+
+      data[at - addr] = 0xcc
+    }
+  }
+
+  _, e := syscall.PtracePokeData(p.Tid, uintptr(addr), data)
+
+  return e
+}
+
+// executes a single instruction, lifting the breakpoint of the current address
+func (p *Debugger) Step() error {
+  pc, e := p.GetPC()
+
+  if e != nil {
+    return e
+  }
+
+  breakpoint := p.GetBreakpointAt(pc)
+
+  if breakpoint != nil && breakpoint.Inserted {
+    if e := p.Remove(breakpoint); e != nil {
+      return e
+    }
+  }
+
+  e = p.resume(syscall.PTRACE_SINGLESTEP)
+
+  if breakpoint != nil && p.Running {
+    p.Insert(breakpoint)
+  }
+
+  return e
+}
+
+func (p *Debugger) Continue() error {
+  return p.run(syscall.PTRACE_CONT)
+}
+
+// continues until the entry or the exit of the next syscall, decoding it
+func (p *Debugger) ContinueSyscall() error {
+  if e := p.run(syscall.PTRACE_SYSCALL); e != nil || p.SyscallStop == false {
+    return e
+  }
+
+  p.InSyscall = !p.InSyscall
+
+  if p.InSyscall {
+    call, e := p.GetSyscall()
+
+    p.Syscall = call
+
+    return e
+  } else if p.Syscall != nil {
+    return p.CompleteSyscall(p.Syscall)
+  }
+
+  return nil
+}
+
+func (p *Debugger) run(request int) error {
+  pc, e := p.GetPC()
+
+  if e != nil {
+    return e
+  }
+
+  if breakpoint := p.GetBreakpointAt(pc); breakpoint != nil {
+    if e := p.Step(); e != nil || p.Running == false || p.Signal != 0 || p.Watch != nil {
+      return e
+    }
+  }
+
+  for {
+    if e := p.resume(request); e != nil || p.Running == false {
+      return e
+    }
+
+    // the cpu traps on writes too, which are not the ones of a read watchpoint
+    if p.Watch == nil || p.Watch.Kind != "r" || p.Watch.Old == p.Watch.Value {
+      break
+    }
+
+    p.Watch.Hits = p.Watch.Hits - 1
+  }
+
+  if p.Status.StopSignal() != syscall.SIGTRAP || p.Watch != nil || p.Exec {
+    return nil
+  }
+
+  // the int3 was executed, so the pc is one byte after the breakpoint
+  if pc, e = p.GetPC(); e != nil {
+    return e
+  }
+
+  if breakpoint := p.GetBreakpointAt(pc - 1); breakpoint != nil {
+    breakpoint.Hits = breakpoint.Hits + 1
+
+    p.Hit = breakpoint
+
+    if thread := p.GetThread(p.Tid); thread != nil {
+      thread.Reported = true
+    }
+
+    if e := p.SetPC(pc - 1); e != nil || breakpoint.Internal == false {
+      return e
+    }
+
+    // the dynamic linker changed the list of libraries, the process goes on
+    p.Hit = nil
+
+    p.LoadLibraries()
+
+    return p.run(request)
+  }
+
+  return nil
+}
+
+// continues until the address is reached in a frame above the current stack pointer
+func (p *Debugger) RunUntil(addr uint64) error {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return e
+  }
+
+  breakpoint := p.GetBreakpointAt(addr)
+
+  if breakpoint == nil {
+    breakpoint = &Breakpoint{
+      Address: addr - p.Bias, Temporary: true}
+
+    p.Breakpoints = append(p.Breakpoints, breakpoint)
+
+    if e := p.Insert(breakpoint); e != nil {
+      p.Delete(breakpoint)
+
+      return e
+    }
+  }
+
+  tid := p.Tid
+
+  for {
+    if e = p.Continue(); e != nil || p.Running == false || p.Hit != breakpoint {
+      break
+    }
+
+    // the temporary breakpoint only belongs to the thread which set it
+    if breakpoint.Temporary && p.Tid != tid {
+      continue
+    }
+
+    current, e := p.GetRegisters()
+
+    // recursive calls hit the same address in deeper frames
+    if e != nil || current.Rsp >= regs.Rsp {
+      break
+    }
+  }
+
+  if breakpoint.Temporary {
+    if p.Hit == breakpoint {
+      p.Hit = nil
+    }
+
+    p.Delete(breakpoint)
+  }
+
+  return e
+}
+
+// steps over calls, stopping at the next instruction of the current function
+func (p *Debugger) Next() error {
+  pc, e := p.GetPC()
+
+  if e != nil {
+    return e
+  }
+
+  code, e := p.ReadMemory(pc, 16)
+
+  if e != nil {
+    return e
+  }
+
+  mode := 64
+
+  if p.File.Class == elf.ELFCLASS32 {
+    mode = 32
+  }
+
+  ins, e := x86asm.Decode(code, mode)
+
+  if e != nil || ins.Op != x86asm.CALL {
+    return p.Step()
+  }
+
+  return p.RunUntil(pc + uint64(ins.Len))
+}
+
+func (p *Debugger) GetWordSize() uint64 {
+  if p.File.Class == elf.ELFCLASS32 {
+    return 4
+  }
+
+  return 8
+}
+
+func (p *Debugger) ReadWord(addr uint64) (uint64, error) {
+  size := p.GetWordSize()
+
+  data, e := p.ReadMemory(addr, size)
+
+  if e != nil || uint64(len(data)) < size {
+    return 0, err.AddressNotMapped
+  }
+
+  if size == 4 {
+    return uint64(p.File.ByteOrder.Uint32(data)), nil
+  }
+
+  return p.File.ByteOrder.Uint64(data), nil
+}
+
+// finds the slot of the return address, following the prologue
+// (endbr64; push rbp; mov rbp, rsp) when the frame is not complete yet
+func (p *Debugger) GetReturnAddress(start uint64) (uint64, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return 0, e
+  }
+
+  size := p.GetWordSize()
+  slot := regs.Rbp + size
+
+  if start != 0 && regs.Rip >= start && regs.Rip - start < 16 {
+    code, e := p.ReadMemory(start, 16)
+
+    if e != nil {
+      return 0, e
+    }
+
+    addr := start
+
+    // endbr64 or endbr32
+    if len(code) >= 4 && code[0] == 0xf3 && code[1] == 0x0f && code[2] == 0x1e && (code[3] == 0xfa || code[3] == 0xfb) {
+      code = code[4:]
+      addr = addr + 4
+    }
+
+    if regs.Rip <= addr {
+      slot = regs.Rsp
+    } else if len(code) >= 1 && code[0] == 0x55 {
+      code = code[1:]
+      addr = addr + 1
+
+      if len(code) > 0 && code[0] == 0x48 { // rex.w of the 64 bits mov
+        code = code[1:]
+      }
+
+      if regs.Rip <= addr || len(code) < 2 || code[0] != 0x89 || code[1] != 0xe5 {
+        slot = regs.Rsp + size
+      }
+    } else {
+      slot = regs.Rsp
+    }
+  }
+
+  return p.ReadWord(slot)
+}
+
+func (p *Debugger) Finish(start uint64) error {
+  addr, e := p.GetReturnAddress(start)
+
+  if e != nil {
+    return e
+  }
+
+  return p.RunUntil(addr)
+}
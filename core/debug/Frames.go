@@ -0,0 +1,804 @@
+package debug
+
+import (
+  "debug/elf"
+  "encoding/binary"
+  "sort"
+  "syscall"
+)
+
+const (
+  ruleSameValue = iota
+  ruleUndefined
+  ruleOffset
+  ruleValOffset
+  ruleRegister
+  ruleExpression
+  ruleValExpression
+)
+
+// the pointer encodings of .eh_frame
+const (
+  encodingOmit = 0xff
+  encodingPcRel = 0x10
+)
+
+// the registers of the call frame information, in the order of their dwarf numbers
+var dwarfRegisters = []string{
+  "rax", "rdx", "rcx", "rbx", "rsi", "rdi", "rbp", "rsp",
+  "r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15", "rip"}
+
+var compatDwarfRegisters = []string{
+  "rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi", "rip"}
+
+type registerRule struct {
+  Kind int
+  Value int64
+  Expression []byte // evaluated with the cfa on the stack
+}
+
+// a row of the table, the cfa is a register plus an offset, or the result of an
+// expression like in the plt and the signal trampolines
+type frameRow struct {
+  cfaRegister uint64
+  cfaOffset int64
+  cfaExpression []byte
+  rules map[uint64]registerRule
+}
+
+type commonEntry struct {
+  codeAlign uint64
+  dataAlign int64
+  returnRegister uint64
+  encoding byte
+  augmented bool // the fdes have augmentation data
+  signal bool
+  instructions []byte
+}
+
+type frameEntry struct {
+  cie *commonEntry
+  start uint64
+  end uint64
+  instructions []byte
+}
+
+// the call frame information of a file, from .eh_frame and .debug_frame
+type FrameTable struct {
+  entries []frameEntry
+  size uint64
+  order binary.ByteOrder
+}
+
+type frameReader struct {
+  data []byte
+  pos int
+  addr uint64 // the address of the section, for the pc relative pointers
+  order binary.ByteOrder
+  size uint64
+}
+
+func (r *frameReader) done() bool {
+  return r.pos >= len(r.data)
+}
+
+func (r *frameReader) byte() byte {
+  if r.done() {
+    return 0
+  }
+
+  r.pos++
+
+  return r.data[r.pos - 1]
+}
+
+func (r *frameReader) fixed(n int) uint64 {
+  if r.pos + n > len(r.data) {
+    r.pos = len(r.data)
+
+    return 0
+  }
+
+  data := r.data[r.pos:r.pos + n]
+
+  r.pos = r.pos + n
+
+  if n == 2 {
+    return uint64(r.order.Uint16(data))
+  } else if n == 4 {
+    return uint64(r.order.Uint32(data))
+  } else if n == 8 {
+    return r.order.Uint64(data)
+  }
+
+  return uint64(data[0])
+}
+
+func (r *frameReader) uleb() uint64 {
+  var result uint64
+  var shift uint
+
+  for r.done() == false {
+    b := r.byte()
+
+    result = result | uint64(b & 0x7f) << shift
+    shift = shift + 7
+
+    if b & 0x80 == 0 {
+      break
+    }
+  }
+
+  return result
+}
+
+func (r *frameReader) sleb() int64 {
+  var result int64
+  var shift uint
+  var b byte
+
+  for r.done() == false {
+    b = r.byte()
+
+    result = result | int64(b & 0x7f) << shift
+    shift = shift + 7
+
+    if b & 0x80 == 0 {
+      break
+    }
+  }
+
+  if shift < 64 && b & 0x40 != 0 {
+    result = result | -(int64(1) << shift)
+  }
+
+  return result
+}
+
+// a block of bytes preceded by its length
+func (r *frameReader) block() []byte {
+  length := int(r.uleb())
+
+  if length < 0 || r.pos + length > len(r.data) {
+    r.pos = len(r.data)
+
+    return nil
+  }
+
+  r.pos = r.pos + length
+
+  return r.data[r.pos - length:r.pos]
+}
+
+func (r *frameReader) cstring() string {
+  start := r.pos
+
+  for r.done() == false && r.data[r.pos] != 0 {
+    r.pos++
+  }
+
+  text := string(r.data[start:r.pos])
+
+  r.byte()
+
+  return text
+}
+
+// reads a pointer of the encoding, the relative ones are converted to addresses
+func (r *frameReader) pointer(encoding byte) uint64 {
+  if encoding == encodingOmit {
+    return 0
+  }
+
+  position := r.addr + uint64(r.pos)
+
+  var value uint64
+
+  switch encoding & 0x0f {
+    case 0x00: value = r.fixed(int(r.size))
+    case 0x01: value = r.uleb()
+    case 0x02: value = r.fixed(2)
+    case 0x03: value = r.fixed(4)
+    case 0x04: value = r.fixed(8)
+    case 0x09: value = uint64(r.sleb())
+    case 0x0a: value = uint64(int64(int16(r.fixed(2))))
+    case 0x0b: value = uint64(int64(int32(r.fixed(4))))
+    case 0x0c: value = r.fixed(8)
+  }
+
+  if encoding & 0x70 == encodingPcRel {
+    value = value + position
+  }
+
+  if r.size == 4 {
+    value = value & 0xffffffff
+  }
+
+  return value
+}
+
+// loads the call frame information of the file, nil when it has none
+func LoadFrameTable(file *elf.File) *FrameTable {
+  table := &FrameTable{
+    size: 8, order: file.ByteOrder}
+
+  if file.Class == elf.ELFCLASS32 {
+    table.size = 4
+  }
+
+  // the .debug_frame describes the same functions, it is only used without .eh_frame
+  for _, name := range []string{".eh_frame", ".debug_frame"} {
+    section := file.Section(name)
+
+    if section == nil || section.Type == elf.SHT_NOBITS || len(table.entries) > 0 {
+      continue
+    }
+
+    if data, e := section.Data(); e == nil {
+      table.parse(data, section.Addr, name == ".eh_frame")
+    }
+  }
+
+  sort.Slice(table.entries, func(i, j int) bool {
+    return table.entries[i].start < table.entries[j].start
+  })
+
+  return table
+}
+
+func (p *FrameTable) parse(data []byte, addr uint64, eh bool) {
+  cies := map[int]*commonEntry{}
+
+  r := &frameReader{
+    data: data, addr: addr, order: p.order, size: p.size}
+
+  for r.pos + 4 <= len(data) {
+    start := r.pos
+    length := r.fixed(4)
+
+    if length == 0 {
+      if eh {
+        break // the terminator
+      }
+
+      continue
+    }
+
+    offsetSize := 4
+
+    if length == 0xffffffff {
+      length = r.fixed(8)
+      offsetSize = 8
+    }
+
+    end := r.pos + int(length)
+
+    if end > len(data) || end < r.pos {
+      break
+    }
+
+    idPosition := r.pos
+    id := r.fixed(offsetSize)
+
+    entry := &frameReader{
+      data: data[:end], pos: r.pos, addr: addr, order: p.order, size: p.size}
+
+    // eh_frame points back to the cie, debug_frame gives its offset
+    isCie := (eh && id == 0) || (eh == false && (id == 0xffffffff || id == 0xffffffffffffffff))
+
+    if isCie {
+      if cie := p.parseCommon(entry); cie != nil {
+        cies[start] = cie
+      }
+    } else {
+      cieOffset := int(id)
+
+      if eh {
+        cieOffset = idPosition - int(id)
+      }
+
+      cie, found := cies[cieOffset]
+
+      if found == false {
+        cie = p.parseCommonAt(data, cieOffset, addr)
+        cies[cieOffset] = cie
+      }
+
+      if cie != nil {
+        p.parseEntry(entry, cie, eh)
+      }
+    }
+
+    r.pos = end
+  }
+}
+
+func (p *FrameTable) parseCommonAt(data []byte, offset int, addr uint64) *commonEntry {
+  if offset < 0 || offset + 4 > len(data) {
+    return nil
+  }
+
+  r := &frameReader{
+    data: data, pos: offset, addr: addr, order: p.order, size: p.size}
+
+  length := r.fixed(4)
+  offsetSize := 4
+
+  if length == 0xffffffff {
+    length = r.fixed(8)
+    offsetSize = 8
+  }
+
+  end := r.pos + int(length)
+
+  if end > len(data) {
+    return nil
+  }
+
+  r.data = data[:end]
+  r.fixed(offsetSize)
+
+  return p.parseCommon(r)
+}
+
+func (p *FrameTable) parseCommon(r *frameReader) *commonEntry {
+  cie := &commonEntry{}
+
+  version := r.byte()
+  augmentation := r.cstring()
+
+  if len(augmentation) >= 2 && augmentation[:2] == "eh" {
+    r.fixed(int(p.size))
+  }
+
+  if version >= 4 {
+    r.byte() // address_size
+    r.byte() // segment_size
+  }
+
+  cie.codeAlign = r.uleb()
+  cie.dataAlign = r.sleb()
+
+  if version == 1 {
+    cie.returnRegister = uint64(r.byte())
+  } else {
+    cie.returnRegister = r.uleb()
+  }
+
+  if len(augmentation) > 0 && augmentation[0] == 'z' {
+    cie.augmented = true
+
+    length := r.uleb()
+    end := r.pos + int(length)
+
+    for _, c := range augmentation[1:] {
+      if c == 'R' {
+        cie.encoding = r.byte()
+      } else if c == 'P' {
+        r.pointer(r.byte())
+      } else if c == 'L' {
+        r.byte()
+      } else if c == 'S' {
+        cie.signal = true
+      } else {
+        break
+      }
+    }
+
+    r.pos = end
+  } else if len(augmentation) > 0 && augmentation != "eh" {
+    return nil // unknown data before the instructions
+  }
+
+  if r.pos > len(r.data) {
+    return nil
+  }
+
+  cie.instructions = r.data[r.pos:]
+
+  return cie
+}
+
+func (p *FrameTable) parseEntry(r *frameReader, cie *commonEntry, eh bool) {
+  var start, length uint64
+
+  if eh {
+    start = r.pointer(cie.encoding)
+    length = r.pointer(cie.encoding & 0x0f)
+
+    if cie.augmented {
+      r.pos = r.pos + int(r.uleb())
+    }
+  } else {
+    start = r.fixed(int(p.size))
+    length = r.fixed(int(p.size))
+  }
+
+  if start == 0 || r.pos > len(r.data) {
+    return
+  }
+
+  p.entries = append(p.entries, frameEntry{
+    cie: cie, start: start, end: start + length, instructions: r.data[r.pos:]})
+}
+
+func (p *FrameTable) getEntry(pc uint64) *frameEntry {
+  i := sort.Search(len(p.entries), func(i int) bool {
+    return p.entries[i].end > pc
+  })
+
+  // the ranges of the entries do not overlap
+  if i < len(p.entries) && p.entries[i].start <= pc {
+    return &p.entries[i]
+  }
+
+  return nil
+}
+
+func copyRules(rules map[uint64]registerRule) map[uint64]registerRule {
+  result := map[uint64]registerRule{}
+
+  for k, v := range rules {
+    result[k] = v
+  }
+
+  return result
+}
+
+// runs the instructions of the cie and then the ones of the fde, until the row
+// of the pc
+func (p *FrameTable) getRow(entry *frameEntry, pc uint64) frameRow {
+  row := frameRow{
+    rules: map[uint64]registerRule{}}
+
+  p.execute(&row, nil, entry.cie, entry.cie.instructions, entry.start, ^uint64(0))
+
+  initial := copyRules(row.rules)
+
+  p.execute(&row, initial, entry.cie, entry.instructions, entry.start, pc)
+
+  return row
+}
+
+func (p *FrameTable) execute(row *frameRow, initial map[uint64]registerRule, cie *commonEntry, instructions []byte, location, pc uint64) {
+  var stack []frameRow
+
+  r := &frameReader{
+    data: instructions, order: p.order, size: p.size}
+
+  restore := func(register uint64) {
+    if rule, found := initial[register]; found {
+      row.rules[register] = rule
+    } else {
+      delete(row.rules, register)
+    }
+  }
+
+  for r.done() == false {
+    op := r.byte()
+
+    var advance uint64
+
+    if op & 0xc0 == 0x40 { // advance_loc
+      advance = uint64(op & 0x3f)
+    } else if op & 0xc0 == 0x80 { // offset
+      row.rules[uint64(op & 0x3f)] = registerRule{ruleOffset, int64(r.uleb()) * cie.dataAlign, nil}
+    } else if op & 0xc0 == 0xc0 { // restore
+      restore(uint64(op & 0x3f))
+    } else {
+      switch op {
+        case 0x01: location = r.pointer(cie.encoding) // set_loc
+        case 0x02: advance = r.fixed(1)
+        case 0x03: advance = r.fixed(2)
+        case 0x04: advance = r.fixed(4)
+        case 0x05: // offset_extended
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleOffset, int64(r.uleb()) * cie.dataAlign, nil}
+        case 0x06: restore(r.uleb())
+        case 0x07: row.rules[r.uleb()] = registerRule{ruleUndefined, 0, nil}
+        case 0x08: row.rules[r.uleb()] = registerRule{ruleSameValue, 0, nil}
+        case 0x09: // register
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleRegister, int64(r.uleb()), nil}
+        case 0x0a: // remember_state
+          stack = append(stack, frameRow{cfaRegister: row.cfaRegister, cfaOffset: row.cfaOffset, cfaExpression: row.cfaExpression, rules: copyRules(row.rules)})
+        case 0x0b: // restore_state
+          if len(stack) > 0 {
+            *row = stack[len(stack) - 1]
+            stack = stack[:len(stack) - 1]
+          }
+        case 0x0c: // def_cfa
+          row.cfaRegister = r.uleb()
+          row.cfaOffset = int64(r.uleb())
+          row.cfaExpression = nil
+        case 0x0d: row.cfaRegister = r.uleb()
+        case 0x0e: row.cfaOffset = int64(r.uleb())
+        case 0x0f: // def_cfa_expression
+          row.cfaExpression = r.block()
+        case 0x10: // expression
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleExpression, 0, r.block()}
+        case 0x11: // offset_extended_sf
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleOffset, r.sleb() * cie.dataAlign, nil}
+        case 0x12: // def_cfa_sf
+          row.cfaRegister = r.uleb()
+          row.cfaOffset = r.sleb() * cie.dataAlign
+          row.cfaExpression = nil
+        case 0x13: row.cfaOffset = r.sleb() * cie.dataAlign
+        case 0x14: // val_offset
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleValOffset, int64(r.uleb()) * cie.dataAlign, nil}
+        case 0x15: // val_offset_sf
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleValOffset, r.sleb() * cie.dataAlign, nil}
+        case 0x16: // val_expression
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleValExpression, 0, r.block()}
+        case 0x2e: r.uleb() // GNU_args_size
+        case 0x2f: // GNU_negative_offset_extended
+          register := r.uleb()
+          row.rules[register] = registerRule{ruleOffset, -int64(r.uleb()) * cie.dataAlign, nil}
+      }
+    }
+
+    if advance > 0 {
+      if location + advance * cie.codeAlign > pc {
+        return
+      }
+
+      location = location + advance * cie.codeAlign
+    }
+  }
+}
+
+// a frame of the stack, the pc is the return address in the callers
+type Frame struct {
+  Pc uint64 `json:"pc"`
+  Cfa uint64 `json:"cfa"`
+}
+
+// finds the table of the file containing the pc and the bias of its addresses
+type frameLookup func(pc uint64) (*FrameTable, uint64)
+
+// unwinds the stack from the registers, with the call frame information of
+// the file of each pc, or else with the chain of the frame pointers
+func unwind(memory interface{ ReadMemory(addr, length uint64) ([]byte, error) }, regs syscall.PtraceRegs, size uint64, lookup frameLookup) []Frame {
+  names := dwarfRegisters
+  sp, fp, ra := uint64(7), uint64(6), uint64(16)
+
+  if size == 4 {
+    names = compatDwarfRegisters
+    sp, fp, ra = 4, 5, 8
+  }
+
+  values := map[uint64]uint64{}
+
+  for i, name := range names {
+    values[uint64(i)] = *GetRegisterField(&regs, name)
+  }
+
+  read := func(addr uint64) (uint64, bool) {
+    data, e := memory.ReadMemory(addr, size)
+
+    if e != nil || uint64(len(data)) < size {
+      return 0, false
+    }
+
+    if size == 4 {
+      return uint64(binary.LittleEndian.Uint32(data)), true
+    }
+
+    return binary.LittleEndian.Uint64(data), true
+  }
+
+  var frames []Frame
+
+  signal := false
+
+  for depth := 0; depth < 256; depth++ {
+    pc := values[ra]
+
+    if pc == 0 {
+      break
+    }
+
+    // the return address is after the call, which may be the last instruction of the function
+    lookupPc := pc
+
+    if depth > 0 && signal == false {
+      lookupPc = pc - 1
+    }
+
+    next := map[uint64]uint64{}
+    var cfa uint64
+
+    table, bias := lookup(lookupPc)
+
+    var entry *frameEntry
+
+    if table != nil {
+      entry = table.getEntry(lookupPc - bias)
+    }
+
+    if entry != nil {
+      row := table.getRow(entry, lookupPc - bias)
+
+      context := &LocationContext{
+        Memory: memory, Size: size}
+
+      for register, value := range values {
+        *GetRegisterField(&context.Registers, names[register]) = value
+      }
+
+      // the unwinding stops at the expressions which are not supported
+      if row.cfaExpression != nil {
+        location, e := evaluate(row.cfaExpression, context, nil)
+
+        if e != nil || location.Kind != LocationMemory {
+          frames = append(frames, Frame{pc, 0})
+
+          break
+        }
+
+        cfa = location.Address
+      } else if base, found := values[row.cfaRegister]; found {
+        cfa = base + uint64(row.cfaOffset)
+      } else {
+        break
+      }
+
+      for register, value := range values {
+        next[register] = value
+      }
+
+      for register, rule := range row.rules {
+        if rule.Kind == ruleExpression || rule.Kind == ruleValExpression {
+          location, e := evaluate(rule.Expression, context, []uint64{cfa})
+
+          if e != nil || location.Kind != LocationMemory {
+            delete(next, register)
+          } else if rule.Kind == ruleValExpression {
+            next[register] = location.Address
+          } else if value, ok := read(location.Address); ok {
+            next[register] = value
+          } else {
+            delete(next, register)
+          }
+        } else if rule.Kind == ruleOffset {
+          if value, ok := read(uint64(int64(cfa) + rule.Value)); ok {
+            next[register] = value
+          } else {
+            delete(next, register)
+          }
+        } else if rule.Kind == ruleValOffset {
+          next[register] = uint64(int64(cfa) + rule.Value)
+        } else if rule.Kind == ruleRegister {
+          next[register] = values[uint64(rule.Value)]
+        } else if rule.Kind == ruleUndefined {
+          delete(next, register)
+        }
+      }
+
+      // the outermost frame has no return address
+      if _, found := next[entry.cie.returnRegister]; found == false {
+        frames = append(frames, Frame{pc, cfa})
+
+        break
+      }
+
+      next[ra] = next[entry.cie.returnRegister]
+      next[sp] = cfa
+
+      signal = entry.cie.signal
+    } else {
+      // push rbp; mov rbp, rsp
+      base, found := values[fp]
+
+      if found == false || base == 0 {
+        frames = append(frames, Frame{pc, 0})
+
+        break
+      }
+
+      cfa = base + 2*size
+
+      caller, ok1 := read(base + size)
+      saved, ok2 := read(base)
+
+      if ok1 == false || ok2 == false {
+        frames = append(frames, Frame{pc, cfa})
+
+        break
+      }
+
+      for register, value := range values {
+        next[register] = value
+      }
+
+      next[ra], next[fp], next[sp] = caller, saved, cfa
+
+      signal = false
+    }
+
+    frames = append(frames, Frame{pc, cfa})
+
+    // the stack grows down, the callers are above
+    if next[sp] <= values[sp] && depth > 0 {
+      break
+    }
+
+    values = next
+  }
+
+  return frames
+}
+
+func (p *Library) getFrameTable() *FrameTable {
+  if p.frames == nil {
+    p.frames = &FrameTable{}
+
+    if file, e := elf.Open(p.Name); e == nil {
+      p.frames = LoadFrameTable(file)
+
+      file.Close()
+    }
+  }
+
+  return p.frames
+}
+
+func (p *Debugger) getFrameTable(pc uint64) (*FrameTable, uint64) {
+  if library := getLibraryAt(p.Libraries, pc); library != nil {
+    return library.getFrameTable(), library.Base
+  }
+
+  if p.frames == nil {
+    p.frames = LoadFrameTable(p.File)
+  }
+
+  return p.frames, p.Bias
+}
+
+// the frames of the current thread, the first one is the one of the pc
+func (p *Debugger) Backtrace() ([]Frame, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return nil, e
+  }
+
+  return unwind(p, regs, p.GetWordSize(), p.getFrameTable), nil
+}
+
+func (p *Core) getFrameTable(pc uint64) (*FrameTable, uint64) {
+  if library := getLibraryAt(p.Libraries, pc); library != nil {
+    return library.getFrameTable(), library.Base
+  }
+
+  if p.executable == nil {
+    return nil, 0
+  }
+
+  if p.frames == nil {
+    p.frames = LoadFrameTable(p.executable)
+  }
+
+  return p.frames, p.Bias
+}
+
+func (p *Core) Backtrace() ([]Frame, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return nil, e
+  }
+
+  size := uint64(8)
+
+  if p.File.Class == elf.ELFCLASS32 {
+    size = 4
+  }
+
+  return unwind(p, regs, size, p.getFrameTable), nil
+}
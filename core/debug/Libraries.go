@@ -0,0 +1,382 @@
+package debug
+
+import (
+  "bytes"
+  "debug/elf"
+  "io/ioutil"
+  "strconv"
+  "strings"
+
+  "jelf/core/err"
+)
+
+const (
+  atBase = 7 // the load address of the dynamic linker in the auxiliary vector
+  rtConsistent = 0
+)
+
+// a shared object of the link_map of the dynamic linker, the base is the
+// difference between its addresses in the process and the ones of its file
+type Library struct {
+  Name string `json:"name"`
+  Base uint64 `json:"base"`
+  Dynamic uint64 `json:"dynamic"`
+  Start uint64 `json:"start"`
+  End uint64 `json:"end"`
+  Symbols []elf.Symbol `json:"-"`
+
+  frames *FrameTable
+}
+
+func OpenLibrary(name string, base uint64) *Library {
+  library := &Library{
+    Name: name, Base: base}
+
+  file, e := elf.Open(name)
+
+  if e != nil {
+    return library // the vdso has no file
+  }
+
+  defer file.Close()
+
+  library.Start = ^uint64(0)
+
+  for _, prog := range file.Progs {
+    if prog.Type == elf.PT_LOAD {
+      if prog.Vaddr + base < library.Start {
+        library.Start = prog.Vaddr + base
+      }
+
+      if prog.Vaddr + prog.Memsz + base > library.End {
+        library.End = prog.Vaddr + prog.Memsz + base
+      }
+    }
+  }
+
+  if library.Start > library.End {
+    library.Start = 0
+  }
+
+  if symbols, e := file.Symbols(); e == nil {
+    library.Symbols = symbols
+  }
+
+  if symbols, e := file.DynamicSymbols(); e == nil {
+    library.Symbols = append(library.Symbols, symbols...)
+  }
+
+  return library
+}
+
+func (p *Debugger) GetLibraryAt(addr uint64) *Library {
+  return getLibraryAt(p.Libraries, addr)
+}
+
+func getLibraryAt(libraries []*Library, addr uint64) *Library {
+  for _, library := range libraries {
+    if addr >= library.Start && addr < library.End {
+      return library
+    }
+  }
+
+  return nil
+}
+
+func isCode(symbol elf.Symbol) bool {
+  t := elf.ST_TYPE(symbol.Info)
+
+  return symbol.Section != elf.SHN_UNDEF && len(symbol.Name) > 0 && (t == elf.STT_FUNC || t == elf.STT_OBJECT || t == elf.STT_GNU_IFUNC)
+}
+
+func getRank(symbol elf.Symbol) int {
+  rank := 0
+
+  if elf.ST_BIND(symbol.Info) != elf.STB_LOCAL {
+    rank = rank + 2
+  }
+
+  if strings.HasPrefix(symbol.Name, "_") == false {
+    rank = rank + 1
+  }
+
+  return rank
+}
+
+// finds the function or object of a library containing the address of the process
+func (p *Debugger) GetLibrarySymbol(addr uint64) (string, uint64, error) {
+  return getLibrarySymbol(p.Libraries, addr)
+}
+
+func getLibrarySymbol(libraries []*Library, addr uint64) (string, uint64, error) {
+  library := getLibraryAt(libraries, addr)
+
+  if library == nil {
+    return "", 0, err.NoSymbolFound
+  }
+
+  var best *elf.Symbol
+
+  for i, symbol := range library.Symbols {
+    value := symbol.Value + library.Base
+
+    if isCode(symbol) == false || value > addr || (symbol.Size > 0 && addr >= value + symbol.Size) {
+      continue
+    }
+
+    if symbol.Size == 0 && value != addr {
+      continue
+    }
+
+    // aliases share the address, the public names are the ones of the api
+    if best == nil || symbol.Value > best.Value || (symbol.Value == best.Value && getRank(symbol) > getRank(*best)) {
+      best = &library.Symbols[i]
+    }
+  }
+
+  if best == nil {
+    return "", 0, err.NoSymbolFound
+  }
+
+  return best.Name, addr - best.Value - library.Base, nil
+}
+
+// the address in the process of a symbol defined by a library
+func (p *Debugger) GetLibrarySymbolAddress(name string) (uint64, error) {
+  return getLibrarySymbolAddress(p.Libraries, name)
+}
+
+func getLibrarySymbolAddress(libraries []*Library, name string) (uint64, error) {
+  for _, library := range libraries {
+    for _, symbol := range library.Symbols {
+      if symbol.Name == name && isCode(symbol) && elf.ST_BIND(symbol.Info) != elf.STB_LOCAL {
+        return symbol.Value + library.Base, nil
+      }
+    }
+  }
+
+  return 0, err.SymbolNotFound
+}
+
+// reads the r_debug of the dynamic linker, from the DT_DEBUG entry of the dynamic section
+func (p *Debugger) GetDebugAddress() uint64 {
+  size := p.GetWordSize()
+
+  for _, prog := range p.File.Progs {
+    if prog.Type != elf.PT_DYNAMIC {
+      continue
+    }
+
+    data, e := p.ReadMemory(prog.Vaddr + p.Bias, prog.Memsz)
+
+    if e != nil {
+      return 0
+    }
+
+    for i := uint64(0); i + 2*size <= uint64(len(data)); i = i + 2*size {
+      tag, value := p.readWord(data[i:]), p.readWord(data[i + size:])
+
+      if elf.DynTag(tag) == elf.DT_NULL {
+        break
+      } else if elf.DynTag(tag) == elf.DT_DEBUG {
+        return value
+      }
+    }
+  }
+
+  return 0
+}
+
+func (p *Debugger) readWord(data []byte) uint64 {
+  if p.GetWordSize() == 4 {
+    return uint64(p.File.ByteOrder.Uint32(data))
+  }
+
+  return p.File.ByteOrder.Uint64(data)
+}
+
+// walks the link_map of r_debug, keeping the symbols of the libraries already loaded
+func (p *Debugger) LoadLibraries() error {
+  rdebug := p.GetDebugAddress()
+
+  if rdebug == 0 {
+    return err.LibraryNotLoaded
+  }
+
+  size := p.GetWordSize()
+
+  state, e := p.ReadWord(rdebug + 3*size)
+
+  if e != nil {
+    return e
+  }
+
+  // the list is being changed, like when attaching during a dlopen. It is
+  // loaded when the breakpoint of r_brk is hit at the end of the change
+  if state != rtConsistent {
+    p.watchDebugBreak(rdebug)
+
+    return nil
+  }
+
+  entry, e := p.ReadWord(rdebug + size)
+
+  if e != nil {
+    return e
+  }
+
+  var libraries []*Library
+
+  for i:=0; entry != 0 && i < 1024; i++ {
+    base, e1 := p.ReadWord(entry)
+    name, e2 := p.ReadWord(entry + size)
+    dynamic, e3 := p.ReadWord(entry + 2*size)
+    next, e4 := p.ReadWord(entry + 3*size)
+
+    if e1 != nil || e2 != nil || e3 != nil || e4 != nil {
+      break
+    }
+
+    // the first one is the executable
+    if path, _, e := p.ReadString(name, 4096); e == nil && len(path) > 0 {
+      library := p.getLibrary(path, base)
+
+      library.Dynamic = dynamic
+
+      libraries = append(libraries, library)
+    }
+
+    entry = next
+  }
+
+  // the breakpoints of the unloaded libraries are gone with their memory
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Library != "" && breakpoint.Inserted {
+      found := false
+
+      for _, library := range libraries {
+        found = found || library.Name == breakpoint.Library
+      }
+
+      breakpoint.Inserted = breakpoint.Inserted && found
+    }
+  }
+
+  p.Libraries = libraries
+
+  for _, breakpoint := range p.Breakpoints {
+    p.Insert(breakpoint)
+  }
+
+  p.watchDebugBreak(rdebug)
+
+  return nil
+}
+
+// r_brk is called by the dynamic linker after each change of the list
+func (p *Debugger) watchDebugBreak(rdebug uint64) {
+  if brk, e := p.ReadWord(rdebug + 2*p.GetWordSize()); e == nil && brk != 0 && p.GetBreakpointAt(brk) == nil {
+    p.addInternalBreakpoint(brk)
+  }
+}
+
+func (p *Debugger) getLibrary(name string, base uint64) *Library {
+  for _, library := range p.Libraries {
+    if library.Name == name && library.Base == base {
+      return library
+    }
+  }
+
+  return OpenLibrary(name, base)
+}
+
+func (p *Debugger) addInternalBreakpoint(addr uint64) error {
+  breakpoint := &Breakpoint{
+    Address: addr - p.Bias, Internal: true}
+
+  if library := p.GetLibraryAt(addr); library != nil {
+    breakpoint.Address = addr - library.Base
+    breakpoint.Library = library.Name
+  }
+
+  if e := p.Insert(breakpoint); e != nil {
+    return e
+  }
+
+  p.Breakpoints = append(p.Breakpoints, breakpoint)
+
+  return nil
+}
+
+// follows the loads of the libraries, from the list of the dynamic linker when
+// it is ready or else from a breakpoint in _dl_debug_state, which is called
+// by the linker after each change of the list
+func (p *Debugger) WatchLibraries() {
+  var breakpoints []*Breakpoint
+
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Internal == false {
+      breakpoint.Inserted = breakpoint.Inserted && breakpoint.Library == ""
+
+      breakpoints = append(breakpoints, breakpoint)
+    }
+  }
+
+  p.Breakpoints = breakpoints
+  p.Libraries = nil
+
+  if p.LoadLibraries() == nil {
+    return
+  }
+
+  var interpreter string
+
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_INTERP {
+      if data, e := ioutil.ReadAll(prog.Open()); e == nil {
+        interpreter = string(bytes.TrimRight(data, "\x00"))
+      }
+    }
+  }
+
+  base := p.getAuxiliaryValue(atBase)
+
+  if len(interpreter) == 0 || base == 0 {
+    return // a static executable
+  }
+
+  linker := OpenLibrary(interpreter, base)
+
+  p.Libraries = []*Library{linker}
+
+  for _, symbol := range linker.Symbols {
+    if symbol.Name == "_dl_debug_state" && isCode(symbol) {
+      p.addInternalBreakpoint(symbol.Value + base)
+
+      break
+    }
+  }
+}
+
+func (p *Debugger) getAuxiliaryValue(key uint64) uint64 {
+  data, e := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/auxv")
+
+  if e != nil {
+    return 0
+  }
+
+  size := p.GetWordSize()
+
+  for i := uint64(0); i + 2*size <= uint64(len(data)); i = i + 2*size {
+    if p.readWord(data[i:]) == key {
+      return p.readWord(data[i + size:])
+    }
+  }
+
+  return 0
+}
+
+// the short name of the library, like libc.so.6
+func (library *Library) GetShortName() string {
+  return library.Name[strings.LastIndex(library.Name, "/") + 1:]
+}
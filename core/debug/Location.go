@@ -0,0 +1,216 @@
+package debug
+
+import (
+  "encoding/binary"
+  "syscall"
+
+  "jelf/core/err"
+)
+
+const (
+  LocationMemory = iota // the variable is in memory at the address
+  LocationRegister // the variable is in the register
+  LocationValue // the expression computes the value, not its place
+)
+
+// where a variable lives, from a dwarf location expression
+type Location struct {
+  Kind int
+  Address uint64
+  Register string
+  Value uint64
+}
+
+// the state of the frame needed by the location expressions
+type LocationContext struct {
+  Registers syscall.PtraceRegs
+  Memory interface{ ReadMemory(addr, length uint64) ([]byte, error) }
+  FrameBase uint64
+  Cfa uint64
+  Bias uint64 // added to the addresses of DW_OP_addr
+  Size uint64
+}
+
+// the name of a register from its dwarf number
+func GetDwarfRegister(number, size uint64) (string, error) {
+  names := dwarfRegisters
+
+  if size == 4 {
+    names = compatDwarfRegisters
+  }
+
+  if number >= uint64(len(names)) {
+    return "", err.RegisterNotFound
+  }
+
+  return names[number], nil
+}
+
+func (p *LocationContext) getRegister(number uint64) (uint64, error) {
+  name, e := GetDwarfRegister(number, p.Size)
+
+  if e != nil {
+    return 0, e
+  }
+
+  return *GetRegisterField(&p.Registers, name), nil
+}
+
+func (p *LocationContext) readWord(addr uint64) (uint64, error) {
+  if p.Memory == nil {
+    return 0, err.NotRunning
+  }
+
+  data, e := p.Memory.ReadMemory(addr, p.Size)
+
+  if e != nil {
+    return 0, e
+  }
+
+  if uint64(len(data)) < p.Size {
+    return 0, err.AddressNotMapped
+  }
+
+  if p.Size == 4 {
+    return uint64(binary.LittleEndian.Uint32(data)), nil
+  }
+
+  return binary.LittleEndian.Uint64(data), nil
+}
+
+// evaluates the usual operations of the location expressions, the pieces and
+// the operations of the optimized code are not supported
+func EvaluateLocation(expr []byte, context *LocationContext) (Location, error) {
+  return evaluate(expr, context, nil)
+}
+
+// the expressions of the call frame information start with the cfa on the stack
+func evaluate(expr []byte, context *LocationContext, initial []uint64) (Location, error) {
+  stack := append([]uint64{}, initial...)
+
+  r := &frameReader{
+    data: expr, order: binary.LittleEndian, size: context.Size}
+
+  pop := func() uint64 {
+    if len(stack) == 0 {
+      return 0
+    }
+
+    value := stack[len(stack) - 1]
+    stack = stack[:len(stack) - 1]
+
+    return value
+  }
+
+  for r.done() == false {
+    op := r.byte()
+
+    if op >= 0x50 && op <= 0x6f { // reg0-31
+      name, e := GetDwarfRegister(uint64(op - 0x50), context.Size)
+
+      return Location{Kind: LocationRegister, Register: name}, e
+    } else if op >= 0x70 && op <= 0x8f { // breg0-31
+      value, e := context.getRegister(uint64(op - 0x70))
+
+      if e != nil {
+        return Location{}, e
+      }
+
+      stack = append(stack, uint64(int64(value) + r.sleb()))
+    } else if op >= 0x30 && op <= 0x4f { // lit0-31
+      stack = append(stack, uint64(op - 0x30))
+    } else {
+      switch op {
+        case 0x03: stack = append(stack, r.fixed(int(context.Size)) + context.Bias) // addr
+        case 0x06: // deref
+          value, e := context.readWord(pop())
+
+          if e != nil {
+            return Location{}, e
+          }
+
+          stack = append(stack, value)
+        case 0x08: stack = append(stack, r.fixed(1)) // const1u
+        case 0x09: stack = append(stack, uint64(int64(int8(r.fixed(1)))))
+        case 0x0a: stack = append(stack, r.fixed(2))
+        case 0x0b: stack = append(stack, uint64(int64(int16(r.fixed(2)))))
+        case 0x0c: stack = append(stack, r.fixed(4))
+        case 0x0d: stack = append(stack, uint64(int64(int32(r.fixed(4)))))
+        case 0x0e: stack = append(stack, r.fixed(8))
+        case 0x0f: stack = append(stack, r.fixed(8))
+        case 0x10: stack = append(stack, r.uleb()) // constu
+        case 0x11: stack = append(stack, uint64(r.sleb())) // consts
+        case 0x12: // dup
+          value := pop()
+          stack = append(stack, value, value)
+        case 0x13: pop() // drop
+        case 0x14: // over
+          b, a := pop(), pop()
+          stack = append(stack, a, b, a)
+        case 0x16: // swap
+          b, a := pop(), pop()
+          stack = append(stack, b, a)
+        case 0x1a, 0x1c, 0x1e, 0x21, 0x22, 0x24, 0x25, 0x26, 0x27, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e:
+          b, a := pop(), pop()
+          stack = append(stack, binaryOperation(op, a, b))
+        case 0x1f: stack = append(stack, uint64(-int64(pop()))) // neg
+        case 0x20: stack = append(stack, ^pop()) // not
+        case 0x23: stack = append(stack, pop() + r.uleb()) // plus_uconst
+        case 0x90: // regx
+          name, e := GetDwarfRegister(r.uleb(), context.Size)
+
+          return Location{Kind: LocationRegister, Register: name}, e
+        case 0x91: stack = append(stack, uint64(int64(context.FrameBase) + r.sleb())) // fbreg
+        case 0x92: // bregx
+          value, e := context.getRegister(r.uleb())
+
+          if e != nil {
+            return Location{}, e
+          }
+
+          stack = append(stack, uint64(int64(value) + r.sleb()))
+        case 0x96: // nop
+        case 0x9c: stack = append(stack, context.Cfa) // call_frame_cfa
+        case 0x9f: return Location{Kind: LocationValue, Value: pop()}, nil // stack_value
+        default:
+          return Location{}, err.UnsupportedExpression
+      }
+    }
+  }
+
+  if len(stack) == 0 {
+    return Location{}, err.UnsupportedExpression
+  }
+
+  return Location{Kind: LocationMemory, Address: pop()}, nil
+}
+
+// the arithmetic, logical and relational operations on the two top entries
+func binaryOperation(op byte, a, b uint64) uint64 {
+  compare := func(result bool) uint64 {
+    if result {
+      return 1
+    }
+
+    return 0
+  }
+
+  switch op {
+    case 0x1a: return a & b
+    case 0x1c: return a - b
+    case 0x1e: return a * b
+    case 0x21: return a | b
+    case 0x22: return a + b
+    case 0x24: return a << b
+    case 0x25: return a >> b
+    case 0x26: return uint64(int64(a) >> b)
+    case 0x27: return a ^ b
+    case 0x29: return compare(a == b)
+    case 0x2a: return compare(int64(a) >= int64(b))
+    case 0x2b: return compare(int64(a) > int64(b))
+    case 0x2c: return compare(int64(a) <= int64(b))
+    case 0x2d: return compare(int64(a) < int64(b))
+  }
+
+  return compare(a != b) // ne
+}
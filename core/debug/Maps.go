@@ -0,0 +1,62 @@
+package debug
+
+import (
+  "io/ioutil"
+  "strconv"
+  "strings"
+)
+
+type Map struct {
+  Start uint64 `json:"start"`
+  End uint64 `json:"end"`
+  Perms string `json:"perms"`
+  Offset uint64 `json:"offset"`
+  Path string `json:"path"`
+}
+
+func ParseMaps(data string) []Map {
+  var result []Map
+
+  for _, line := range strings.Split(data, "\n") {
+    fields := strings.Fields(line)
+
+    if len(fields) < 5 {
+      continue
+    }
+
+    bounds := strings.Split(fields[0], "-")
+
+    if len(bounds) != 2 {
+      continue
+    }
+
+    start, e1 := strconv.ParseUint(bounds[0], 16, 64)
+    end, e2 := strconv.ParseUint(bounds[1], 16, 64)
+    offset, e3 := strconv.ParseUint(fields[2], 16, 64)
+
+    if e1 != nil || e2 != nil || e3 != nil {
+      continue
+    }
+
+    m := Map{
+      Start: start, End: end, Perms: fields[1], Offset: offset}
+
+    if len(fields) > 5 {
+      m.Path = strings.Join(fields[5:], " ")
+    }
+
+    result = append(result, m)
+  }
+
+  return result
+}
+
+func (p *Debugger) GetMaps() ([]Map, error) {
+  data, e := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/maps")
+
+  if e != nil {
+    return nil, e
+  }
+
+  return ParseMaps(string(data)), nil
+}
@@ -0,0 +1,296 @@
+package debug
+
+import (
+  "encoding/binary"
+  "math"
+  "strings"
+  "syscall"
+  "unsafe"
+
+  "jelf/core/err"
+)
+
+const (
+  ptraceGetFPRegs = 14
+  ptraceGetRegSet = 0x4204
+  ptraceSetRegSet = 0x4205
+  ntPrStatus = 1
+  ntX86XState = 0x202
+  xsaveYmmOffset = 576
+)
+
+var RegisterNames = []string{
+  "rax", "rbx", "rcx", "rdx", "rsi", "rdi", "rbp", "rsp",
+  "r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+  "rip", "eflags", "cs", "ss", "ds", "es", "fs", "gs", "fs_base", "gs_base", "orig_rax"}
+
+// the lower halves of the registers, written with zero extension like the cpu does
+var registerAliases = map[string]string{
+  "eax": "rax", "ebx": "rbx", "ecx": "rcx", "edx": "rdx", "esi": "rsi", "edi": "rdi", "ebp": "rbp", "esp": "rsp",
+  "r8d": "r8", "r9d": "r9", "r10d": "r10", "r11d": "r11", "r12d": "r12", "r13d": "r13", "r14d": "r14", "r15d": "r15",
+  "eip": "rip", "rflags": "eflags", "pc": "rip", "sp": "rsp", "fp": "rbp"}
+
+var flagNames = []struct {
+  Bit uint
+  Name string
+}{
+  {0, "CF"}, {2, "PF"}, {4, "AF"}, {6, "ZF"}, {7, "SF"}, {8, "TF"}, {9, "IF"}, {10, "DF"}, {11, "OF"},
+  {14, "NT"}, {16, "RF"}, {17, "VM"}, {18, "AC"}, {19, "VIF"}, {20, "VIP"}, {21, "ID"}}
+
+type FPRegisters struct {
+  Control uint16 `json:"fcw"`
+  Status uint16 `json:"fsw"`
+  Tag uint16 `json:"ftw"`
+  Mxcsr uint32 `json:"mxcsr"`
+  St [8][10]byte `json:"-"`
+  Xmm [16][16]byte `json:"-"`
+  Ymm [16][16]byte `json:"-"` // upper halves of the ymm registers
+  Avx bool `json:"avx"`
+}
+
+func GetRegisterField(regs *syscall.PtraceRegs, name string) *uint64 {
+  switch name {
+    case "rax": return &regs.Rax
+    case "rbx": return &regs.Rbx
+    case "rcx": return &regs.Rcx
+    case "rdx": return &regs.Rdx
+    case "rsi": return &regs.Rsi
+    case "rdi": return &regs.Rdi
+    case "rbp": return &regs.Rbp
+    case "rsp": return &regs.Rsp
+    case "r8": return &regs.R8
+    case "r9": return &regs.R9
+    case "r10": return &regs.R10
+    case "r11": return &regs.R11
+    case "r12": return &regs.R12
+    case "r13": return &regs.R13
+    case "r14": return &regs.R14
+    case "r15": return &regs.R15
+    case "rip": return &regs.Rip
+    case "eflags": return &regs.Eflags
+    case "cs": return &regs.Cs
+    case "ss": return &regs.Ss
+    case "ds": return &regs.Ds
+    case "es": return &regs.Es
+    case "fs": return &regs.Fs
+    case "gs": return &regs.Gs
+    case "fs_base": return &regs.Fs_base
+    case "gs_base": return &regs.Gs_base
+    case "orig_rax": return &regs.Orig_rax
+  }
+
+  return nil
+}
+
+// returns the register of the full name and if the name is the one of the lower half
+func resolveRegister(name string) (string, bool) {
+  name = strings.ToLower(name)
+
+  if full, found := registerAliases[name]; found {
+    return full, strings.HasPrefix(name, "e") || strings.HasSuffix(name, "d")
+  }
+
+  return name, false
+}
+
+func (p *Debugger) GetRegister(name string) (uint64, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return 0, e
+  }
+
+  full, half := resolveRegister(name)
+
+  field := GetRegisterField(&regs, full)
+
+  if field == nil {
+    return 0, err.RegisterNotFound
+  }
+
+  if half {
+    return *field & 0xffffffff, nil
+  }
+
+  return *field, nil
+}
+
+func (p *Debugger) SetRegister(name string, value uint64) error {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return e
+  }
+
+  full, half := resolveRegister(name)
+
+  field := GetRegisterField(&regs, full)
+
+  if field == nil {
+    return err.RegisterNotFound
+  }
+
+  if half {
+    value = value & 0xffffffff
+  }
+
+  *field = value
+
+  return p.SetRegisters(regs)
+}
+
+func GetFlagsDescription(flags uint64) string {
+  var names []string
+
+  for _, flag := range flagNames {
+    if flags & (1 << flag.Bit) != 0 {
+      names = append(names, flag.Name)
+    }
+  }
+
+  return "[ " + strings.Join(names, " ") + " ]"
+}
+
+func (p *Debugger) ptrace(request int, addr, data uintptr) error {
+  return ptraceRequest(request, p.Tid, addr, data)
+}
+
+func ptraceRequest(request, tid int, addr, data uintptr) error {
+  _, _, errno := syscall.Syscall6(syscall.SYS_PTRACE, uintptr(request), uintptr(tid), addr, data, 0, 0)
+
+  if errno != 0 {
+    return errno
+  }
+
+  return nil
+}
+
+// the order of the registers in the user_regs_struct of i386
+var compatRegisterNames = []string{
+  "rbx", "rcx", "rdx", "rsi", "rdi", "rbp", "rax", "ds", "es", "fs", "gs", "orig_rax", "rip", "cs", "eflags", "rsp", "ss"}
+
+// the regset of a 32 bits process has the i386 layout, which is zero extended
+// to the one of x86-64 so the rest of the debugger has a single view
+func (p *Debugger) getCompatRegisters() (syscall.PtraceRegs, error) {
+  var regs syscall.PtraceRegs
+
+  data := make([]byte, len(compatRegisterNames) * 4)
+
+  iovec := syscall.Iovec{
+    Base: &data[0], Len: uint64(len(data))}
+
+  if e := p.ptrace(ptraceGetRegSet, ntPrStatus, uintptr(unsafe.Pointer(&iovec))); e != nil {
+    return regs, e
+  }
+
+  for i, name := range compatRegisterNames {
+    *GetRegisterField(&regs, name) = uint64(binary.LittleEndian.Uint32(data[i*4:]))
+  }
+
+  // -1 when the process is not in a syscall
+  regs.Orig_rax = uint64(int64(int32(regs.Orig_rax)))
+
+  return regs, nil
+}
+
+func (p *Debugger) setCompatRegisters(regs syscall.PtraceRegs) error {
+  data := make([]byte, len(compatRegisterNames) * 4)
+
+  for i, name := range compatRegisterNames {
+    binary.LittleEndian.PutUint32(data[i*4:], uint32(*GetRegisterField(&regs, name)))
+  }
+
+  iovec := syscall.Iovec{
+    Base: &data[0], Len: uint64(len(data))}
+
+  return p.ptrace(ptraceSetRegSet, ntPrStatus, uintptr(unsafe.Pointer(&iovec)))
+}
+
+// reads the fxsave area and, when the kernel exposes it, the avx state of the xsave area
+func (p *Debugger) GetFPRegisters() (FPRegisters, error) {
+  var result FPRegisters
+
+  if p.Running == false {
+    return result, err.NotRunning
+  }
+
+  data := make([]byte, 4096)
+
+  iovec := syscall.Iovec{
+    Base: &data[0], Len: uint64(len(data))}
+
+  if e := p.ptrace(ptraceGetRegSet, ntX86XState, uintptr(unsafe.Pointer(&iovec))); e == nil && iovec.Len >= xsaveYmmOffset + 256 {
+    return DecodeFPRegisters(data, true), nil
+  } else if e := p.ptrace(ptraceGetFPRegs, 0, uintptr(unsafe.Pointer(&data[0]))); e != nil {
+    return result, e
+  }
+
+  return DecodeFPRegisters(data, false), nil
+}
+
+// decodes the fxsave area, followed by the avx state when it is the start of an xsave one
+func DecodeFPRegisters(data []byte, xsave bool) FPRegisters {
+  var result FPRegisters
+
+  if len(data) < 512 {
+    return result
+  }
+
+  if xsave && len(data) >= xsaveYmmOffset + 256 {
+    result.Avx = true
+
+    // the upper halves are zero while the avx state is not in use
+    if bv := binary.LittleEndian.Uint64(data[512:]); bv & 0x4 != 0 {
+      for i:=0; i<16; i++ {
+        copy(result.Ymm[i][:], data[xsaveYmmOffset + i*16:])
+      }
+    }
+  }
+
+  result.Control = binary.LittleEndian.Uint16(data[0:])
+  result.Status = binary.LittleEndian.Uint16(data[2:])
+  result.Tag = binary.LittleEndian.Uint16(data[4:])
+  result.Mxcsr = binary.LittleEndian.Uint32(data[24:])
+
+  for i:=0; i<8; i++ {
+    copy(result.St[i][:], data[32 + i*16:])
+  }
+
+  for i:=0; i<16; i++ {
+    copy(result.Xmm[i][:], data[160 + i*16:])
+  }
+
+  return result
+}
+
+// converts the 80 bits extended precision of the x87 registers
+func ExtendedToFloat(data [10]byte) float64 {
+  mantissa := binary.LittleEndian.Uint64(data[0:])
+  exponent := int(binary.LittleEndian.Uint16(data[8:]))
+
+  sign := 1.0
+
+  if exponent & 0x8000 != 0 {
+    sign = -1.0
+  }
+
+  exponent = exponent & 0x7fff
+
+  if exponent == 0x7fff {
+    if mantissa << 1 == 0 {
+      return math.Inf(int(sign))
+    }
+
+    return math.NaN()
+  }
+
+  if exponent == 0 && mantissa == 0 {
+    return sign * 0.0
+  }
+
+  if exponent == 0 {
+    exponent = 1 // denormal
+  }
+
+  return sign * math.Ldexp(float64(mantissa), exponent - 16383 - 63)
+}
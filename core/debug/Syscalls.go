@@ -0,0 +1,536 @@
+package debug
+
+import (
+  "debug/elf"
+  "fmt"
+  "strconv"
+  "strings"
+  "syscall"
+)
+
+type syscallInfo struct {
+  Name string
+  Args string // kinds of the arguments, decoded by DecodeArgument
+}
+
+type Syscall struct {
+  Number uint64 `json:"number"`
+  Name string `json:"name"`
+  Args []string `json:"args"`
+  Return int64 `json:"return"`
+  Errno string `json:"errno,omitempty"`
+  Compat bool `json:"compat"`
+  Returned bool `json:"returned"`
+
+  values [6]uint64
+  kinds []string
+}
+
+var syscalls64 = map[uint64]syscallInfo{
+  0: {"read", "fd obuf size"},
+  1: {"write", "fd buf size"},
+  2: {"open", "path oflags mode"},
+  3: {"close", "fd"},
+  4: {"stat", "path ptr"},
+  5: {"fstat", "fd ptr"},
+  6: {"lstat", "path ptr"},
+  7: {"poll", "ptr int int"},
+  8: {"lseek", "fd int whence"},
+  9: {"mmap", "ptr size prot mflags fd hex"},
+  10: {"mprotect", "ptr size prot"},
+  11: {"munmap", "ptr size"},
+  12: {"brk", "ptr"},
+  13: {"rt_sigaction", "signal ptr ptr size"},
+  14: {"rt_sigprocmask", "int ptr ptr size"},
+  15: {"rt_sigreturn", ""},
+  16: {"ioctl", "fd hex ptr"},
+  17: {"pread64", "fd obuf size int"},
+  18: {"pwrite64", "fd buf size int"},
+  19: {"readv", "fd ptr int"},
+  20: {"writev", "fd ptr int"},
+  21: {"access", "path amode"},
+  22: {"pipe", "ptr"},
+  23: {"select", "int ptr ptr ptr ptr"},
+  24: {"sched_yield", ""},
+  25: {"mremap", "ptr size size hex ptr"},
+  28: {"madvise", "ptr size int"},
+  32: {"dup", "fd"},
+  33: {"dup2", "fd fd"},
+  34: {"pause", ""},
+  35: {"nanosleep", "ptr ptr"},
+  39: {"getpid", ""},
+  41: {"socket", "int int int"},
+  42: {"connect", "fd ptr int"},
+  43: {"accept", "fd ptr ptr"},
+  44: {"sendto", "fd buf size hex ptr int"},
+  45: {"recvfrom", "fd obuf size hex ptr ptr"},
+  46: {"sendmsg", "fd ptr hex"},
+  47: {"recvmsg", "fd ptr hex"},
+  48: {"shutdown", "fd int"},
+  49: {"bind", "fd ptr int"},
+  50: {"listen", "fd int"},
+  56: {"clone", "hex ptr ptr ptr hex"},
+  57: {"fork", ""},
+  58: {"vfork", ""},
+  59: {"execve", "path argv ptr"},
+  60: {"exit", "int"},
+  61: {"wait4", "int ptr hex ptr"},
+  62: {"kill", "int signal"},
+  63: {"uname", "ptr"},
+  72: {"fcntl", "fd int hex"},
+  78: {"getdents", "fd ptr size"},
+  79: {"getcwd", "obuf size"},
+  80: {"chdir", "path"},
+  82: {"rename", "path path"},
+  83: {"mkdir", "path mode"},
+  84: {"rmdir", "path"},
+  87: {"unlink", "path"},
+  89: {"readlink", "path obuf size"},
+  90: {"chmod", "path mode"},
+  95: {"umask", "mode"},
+  96: {"gettimeofday", "ptr ptr"},
+  97: {"getrlimit", "int ptr"},
+  102: {"getuid", ""},
+  104: {"getgid", ""},
+  107: {"geteuid", ""},
+  108: {"getegid", ""},
+  110: {"getppid", ""},
+  158: {"arch_prctl", "hex ptr"},
+  186: {"gettid", ""},
+  200: {"tkill", "int signal"},
+  202: {"futex", "ptr int int ptr ptr int"},
+  217: {"getdents64", "fd ptr size"},
+  218: {"set_tid_address", "ptr"},
+  228: {"clock_gettime", "int ptr"},
+  230: {"clock_nanosleep", "int int ptr ptr"},
+  231: {"exit_group", "int"},
+  232: {"epoll_wait", "fd ptr int int"},
+  233: {"epoll_ctl", "fd int fd ptr"},
+  234: {"tgkill", "int int signal"},
+  257: {"openat", "dirfd path oflags mode"},
+  258: {"mkdirat", "dirfd path mode"},
+  262: {"newfstatat", "dirfd path ptr atflags"},
+  263: {"unlinkat", "dirfd path atflags"},
+  267: {"readlinkat", "dirfd path obuf size"},
+  270: {"pselect6", "int ptr ptr ptr ptr ptr"},
+  271: {"ppoll", "ptr int ptr ptr size"},
+  273: {"set_robust_list", "ptr size"},
+  281: {"epoll_pwait", "fd ptr int int ptr size"},
+  288: {"accept4", "fd ptr ptr hex"},
+  290: {"eventfd2", "int hex"},
+  291: {"epoll_create1", "hex"},
+  292: {"dup3", "fd fd hex"},
+  293: {"pipe2", "ptr hex"},
+  302: {"prlimit64", "int int ptr ptr"},
+  318: {"getrandom", "ptr size hex"},
+  332: {"statx", "dirfd path atflags hex ptr"},
+  334: {"rseq", "ptr size hex hex"},
+  435: {"clone3", "ptr size"},
+  439: {"faccessat2", "dirfd path amode atflags"}}
+
+// numbers of the int 0x80 interface
+var syscalls32 = map[uint64]syscallInfo{
+  1: {"exit", "int"},
+  2: {"fork", ""},
+  3: {"read", "fd obuf size"},
+  4: {"write", "fd buf size"},
+  5: {"open", "path oflags mode"},
+  6: {"close", "fd"},
+  7: {"waitpid", "int ptr hex"},
+  10: {"unlink", "path"},
+  11: {"execve", "path argv ptr"},
+  12: {"chdir", "path"},
+  13: {"time", "ptr"},
+  15: {"chmod", "path mode"},
+  19: {"lseek", "fd int whence"},
+  20: {"getpid", ""},
+  33: {"access", "path amode"},
+  37: {"kill", "int signal"},
+  38: {"rename", "path path"},
+  39: {"mkdir", "path mode"},
+  40: {"rmdir", "path"},
+  41: {"dup", "fd"},
+  42: {"pipe", "ptr"},
+  45: {"brk", "ptr"},
+  54: {"ioctl", "fd hex ptr"},
+  55: {"fcntl", "fd int hex"},
+  60: {"umask", "mode"},
+  63: {"dup2", "fd fd"},
+  64: {"getppid", ""},
+  85: {"readlink", "path obuf size"},
+  90: {"mmap", "ptr"},
+  91: {"munmap", "ptr size"},
+  102: {"socketcall", "int ptr"},
+  114: {"wait4", "int ptr hex ptr"},
+  120: {"clone", "hex ptr ptr ptr ptr"},
+  122: {"uname", "ptr"},
+  125: {"mprotect", "ptr size prot"},
+  140: {"_llseek", "fd int int ptr whence"},
+  146: {"writev", "fd ptr int"},
+  162: {"nanosleep", "ptr ptr"},
+  174: {"rt_sigaction", "signal ptr ptr size"},
+  175: {"rt_sigprocmask", "int ptr ptr size"},
+  180: {"pread64", "fd obuf size int"},
+  181: {"pwrite64", "fd buf size int"},
+  183: {"getcwd", "obuf size"},
+  190: {"vfork", ""},
+  192: {"mmap2", "ptr size prot mflags fd hex"},
+  195: {"stat64", "path ptr"},
+  196: {"lstat64", "path ptr"},
+  197: {"fstat64", "fd ptr"},
+  199: {"getuid32", ""},
+  200: {"getgid32", ""},
+  201: {"geteuid32", ""},
+  202: {"getegid32", ""},
+  220: {"getdents64", "fd ptr size"},
+  221: {"fcntl64", "fd int hex"},
+  224: {"gettid", ""},
+  240: {"futex", "ptr int int ptr ptr int"},
+  243: {"set_thread_area", "ptr"},
+  252: {"exit_group", "int"},
+  258: {"set_tid_address", "ptr"},
+  265: {"clock_gettime", "int ptr"},
+  270: {"tgkill", "int int signal"},
+  295: {"openat", "dirfd path oflags mode"},
+  300: {"fstatat64", "dirfd path ptr atflags"},
+  311: {"set_robust_list", "ptr size"},
+  355: {"getrandom", "ptr size hex"},
+  383: {"statx", "dirfd path atflags hex ptr"},
+  384: {"arch_prctl", "hex ptr"},
+  386: {"rseq", "ptr size hex hex"},
+  403: {"clock_gettime64", "int ptr"},
+  435: {"clone3", "ptr size"}}
+
+var errnoNames = map[int64]string{
+  1: "EPERM", 2: "ENOENT", 3: "ESRCH", 4: "EINTR", 5: "EIO", 6: "ENXIO", 7: "E2BIG", 8: "ENOEXEC",
+  9: "EBADF", 10: "ECHILD", 11: "EAGAIN", 12: "ENOMEM", 13: "EACCES", 14: "EFAULT", 16: "EBUSY",
+  17: "EEXIST", 18: "EXDEV", 19: "ENODEV", 20: "ENOTDIR", 21: "EISDIR", 22: "EINVAL", 23: "ENFILE",
+  24: "EMFILE", 25: "ENOTTY", 26: "ETXTBSY", 27: "EFBIG", 28: "ENOSPC", 29: "ESPIPE", 30: "EROFS",
+  31: "EMLINK", 32: "EPIPE", 34: "ERANGE", 36: "ENAMETOOLONG", 38: "ENOSYS", 39: "ENOTEMPTY",
+  40: "ELOOP", 61: "ENODATA", 95: "EOPNOTSUPP", 97: "EAFNOSUPPORT", 98: "EADDRINUSE",
+  104: "ECONNRESET", 110: "ETIMEDOUT", 111: "ECONNREFUSED", 115: "EINPROGRESS"}
+
+var signalNames = []string{
+  "", "SIGHUP", "SIGINT", "SIGQUIT", "SIGILL", "SIGTRAP", "SIGABRT", "SIGBUS", "SIGFPE", "SIGKILL",
+  "SIGUSR1", "SIGSEGV", "SIGUSR2", "SIGPIPE", "SIGALRM", "SIGTERM", "SIGSTKFLT", "SIGCHLD", "SIGCONT",
+  "SIGSTOP", "SIGTSTP", "SIGTTIN", "SIGTTOU", "SIGURG", "SIGXCPU", "SIGXFSZ", "SIGVTALRM", "SIGPROF",
+  "SIGWINCH", "SIGIO", "SIGPWR", "SIGSYS"}
+
+type flagName struct {
+  Value uint64
+  Name string
+}
+
+var openFlags = []flagName{
+  {0100, "O_CREAT"}, {0200, "O_EXCL"}, {0400, "O_NOCTTY"}, {01000, "O_TRUNC"}, {02000, "O_APPEND"},
+  {04000, "O_NONBLOCK"}, {010000, "O_DSYNC"}, {040000, "O_DIRECT"}, {0200000, "O_DIRECTORY"},
+  {0400000, "O_NOFOLLOW"}, {01000000, "O_NOATIME"}, {02000000, "O_CLOEXEC"}, {010000000, "O_PATH"}}
+
+var protFlags = []flagName{
+  {0x1, "PROT_READ"}, {0x2, "PROT_WRITE"}, {0x4, "PROT_EXEC"}}
+
+var mapFlags = []flagName{
+  {0x1, "MAP_SHARED"}, {0x2, "MAP_PRIVATE"}, {0x10, "MAP_FIXED"}, {0x20, "MAP_ANONYMOUS"},
+  {0x100, "MAP_GROWSDOWN"}, {0x800, "MAP_DENYWRITE"}, {0x4000, "MAP_NORESERVE"}, {0x8000, "MAP_POPULATE"},
+  {0x20000, "MAP_STACK"}, {0x100000, "MAP_FIXED_NOREPLACE"}}
+
+var accessFlags = []flagName{
+  {0x4, "R_OK"}, {0x2, "W_OK"}, {0x1, "X_OK"}}
+
+var atFlags = []flagName{
+  {0x100, "AT_SYMLINK_NOFOLLOW"}, {0x200, "AT_REMOVEDIR"}, {0x400, "AT_SYMLINK_FOLLOW"},
+  {0x800, "AT_NO_AUTOMOUNT"}, {0x1000, "AT_EMPTY_PATH"}}
+
+func GetFlagsText(value uint64, names []flagName) string {
+  var result []string
+
+  for _, flag := range names {
+    if value & flag.Value == flag.Value {
+      result = append(result, flag.Name)
+
+      value = value &^ flag.Value
+    }
+  }
+
+  if value != 0 || len(result) == 0 {
+    result = append(result, fmt.Sprintf("0x%x", value))
+  }
+
+  return strings.Join(result, "|")
+}
+
+// the i386 interface is used by 32 bits processes and by int 0x80 in 64 bits ones
+func (p *Debugger) IsCompat(regs syscall.PtraceRegs) bool {
+  return p.File.Class == elf.ELFCLASS32 || regs.Cs == 0x23
+}
+
+// reads the syscall of the current syscall-stop, decoding the arguments
+func (p *Debugger) GetSyscall() (*Syscall, error) {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return nil, e
+  }
+
+  call := &Syscall{
+    Number: regs.Orig_rax, Compat: p.IsCompat(regs)}
+
+  table := syscalls64
+
+  if call.Compat {
+    table = syscalls32
+
+    call.Number = call.Number & 0xffffffff
+    call.values = [6]uint64{regs.Rbx & 0xffffffff, regs.Rcx & 0xffffffff, regs.Rdx & 0xffffffff, regs.Rsi & 0xffffffff, regs.Rdi & 0xffffffff, regs.Rbp & 0xffffffff}
+  } else {
+    call.values = [6]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9}
+  }
+
+  if info, found := table[call.Number]; found {
+    call.Name = info.Name
+    call.kinds = strings.Fields(info.Args)
+  } else {
+    call.Name = "syscall_" + strconv.FormatUint(call.Number, 10)
+    call.kinds = []string{"hex", "hex", "hex", "hex", "hex", "hex"}
+  }
+
+  for i, kind := range call.kinds {
+    var next uint64
+
+    if i + 1 < len(call.values) {
+      next = call.values[i + 1]
+    }
+
+    if kind == "obuf" {
+      call.Args = append(call.Args, p.DecodeArgument("ptr", call.values[i], 0, call.Compat))
+    } else if kind == "mode" && i > 0 && call.kinds[i - 1] == "oflags" && call.values[i - 1] & (syscall.O_CREAT | 0x410000) == 0 {
+      break // the mode is only used when creating files (O_CREAT or O_TMPFILE)
+    } else {
+      call.Args = append(call.Args, p.DecodeArgument(kind, call.values[i], next, call.Compat))
+    }
+  }
+
+  return call, nil
+}
+
+// completes the syscall with the return value, decoding the buffers written by the kernel
+func (p *Debugger) CompleteSyscall(call *Syscall) error {
+  regs, e := p.GetRegisters()
+
+  if e != nil {
+    return e
+  }
+
+  call.Return = int64(regs.Rax)
+
+  if call.Compat {
+    call.Return = int64(int32(regs.Rax))
+  }
+
+  call.Returned = true
+
+  if call.Return < 0 && call.Return >= -4095 {
+    call.Errno = errnoNames[-call.Return]
+
+    if len(call.Errno) == 0 {
+      call.Errno = "E" + strconv.FormatInt(-call.Return, 10)
+    }
+
+    return nil
+  }
+
+  for i, kind := range call.kinds {
+    if kind == "obuf" {
+      call.Args[i] = p.DecodeArgument("buf", call.values[i], uint64(call.Return), call.Compat)
+    }
+  }
+
+  return nil
+}
+
+func (p *Debugger) ReadString(addr uint64, limit int) (string, bool, error) {
+  data, e := p.ReadMemory(addr, uint64(limit + 1))
+
+  if e != nil {
+    return "", false, e
+  }
+
+  for i, b := range data {
+    if b == 0 {
+      return string(data[:i]), false, nil
+    }
+  }
+
+  if len(data) > limit {
+    data = data[:limit]
+  }
+
+  return string(data), true, nil
+}
+
+func quote(text string, truncated bool) string {
+  result := strconv.Quote(text)
+
+  if truncated {
+    result = result + "..."
+  }
+
+  return result
+}
+
+// next is the value of the following argument, the length of the buffers
+func (p *Debugger) DecodeArgument(kind string, value, next uint64, compat bool) string {
+  if compat {
+    value = value & 0xffffffff
+  }
+
+  switch kind {
+    case "int":
+      if compat {
+        return strconv.FormatInt(int64(int32(value)), 10)
+      }
+
+      return strconv.FormatInt(int64(value), 10)
+    case "size":
+      return strconv.FormatUint(value, 10)
+    case "fd", "dirfd":
+      if int32(value) == -100 && kind == "dirfd" {
+        return "AT_FDCWD"
+      }
+
+      return strconv.FormatInt(int64(int32(value)), 10)
+    case "mode":
+      return fmt.Sprintf("0%o", value)
+    case "ptr":
+      if value == 0 {
+        return "NULL"
+      }
+
+      return fmt.Sprintf("0x%x", value)
+    case "path":
+      if value == 0 {
+        return "NULL"
+      }
+
+      if text, truncated, e := p.ReadString(value, 256); e == nil {
+        return quote(text, truncated)
+      }
+
+      return fmt.Sprintf("0x%x", value)
+    case "buf":
+      if value == 0 {
+        return "NULL"
+      }
+
+      length := next
+
+      if length > 32 {
+        length = 32
+      }
+
+      if data, e := p.ReadMemory(value, length); e == nil {
+        return quote(string(data), next > 32)
+      }
+
+      return fmt.Sprintf("0x%x", value)
+    case "argv":
+      var args []string
+
+      size := uint64(8)
+
+      if compat {
+        size = 4
+      }
+
+      for i:=uint64(0); i<8; i++ {
+        data, e := p.ReadMemory(value + i*size, size)
+
+        if e != nil || uint64(len(data)) < size {
+          break
+        }
+
+        var addr uint64
+
+        if compat {
+          addr = uint64(p.File.ByteOrder.Uint32(data))
+        } else {
+          addr = p.File.ByteOrder.Uint64(data)
+        }
+
+        if addr == 0 {
+          return "[" + strings.Join(args, ", ") + "]"
+        }
+
+        text, truncated, _ := p.ReadString(addr, 64)
+
+        args = append(args, quote(text, truncated))
+      }
+
+      return "[" + strings.Join(args, ", ") + ", ...]"
+    case "oflags":
+      mode := []string{"O_RDONLY", "O_WRONLY", "O_RDWR", "O_ACCMODE"}[value & 0x3]
+
+      if value &^ 0x3 == 0 {
+        return mode
+      }
+
+      return mode + "|" + GetFlagsText(value &^ 0x3, openFlags)
+    case "prot":
+      if value == 0 {
+        return "PROT_NONE"
+      }
+
+      return GetFlagsText(value, protFlags)
+    case "mflags":
+      return GetFlagsText(value, mapFlags)
+    case "amode":
+      if value == 0 {
+        return "F_OK"
+      }
+
+      return GetFlagsText(value, accessFlags)
+    case "atflags":
+      if value == 0 {
+        return "0"
+      }
+
+      return GetFlagsText(value, atFlags)
+    case "whence":
+      if value < 3 {
+        return []string{"SEEK_SET", "SEEK_CUR", "SEEK_END"}[value]
+      }
+
+      return strconv.FormatUint(value, 10)
+    case "signal":
+      if value > 0 && value < uint64(len(signalNames)) {
+        return signalNames[value]
+      } else if value >= 32 && value < 65 {
+        return "SIGRT_" + strconv.FormatUint(value - 32, 10)
+      }
+
+      return strconv.FormatUint(value, 10)
+  }
+
+  return fmt.Sprintf("0x%x", value)
+}
+
+// the text of the return value, with the errno when the syscall failed
+func (call *Syscall) GetReturnText() string {
+  if call.Returned == false {
+    return "?"
+  }
+
+  if len(call.Errno) > 0 {
+    return fmt.Sprintf("-1 %s (%s)", call.Errno, syscall.Errno(-call.Return).Error())
+  }
+
+  if call.Name == "mmap" || call.Name == "mmap2" || call.Name == "brk" || call.Name == "mremap" {
+    return fmt.Sprintf("0x%x", uint64(call.Return))
+  }
+
+  return strconv.FormatInt(call.Return, 10)
+}
+
+func (call *Syscall) String() string {
+  return call.Name + "(" + strings.Join(call.Args, ", ") + ") = " + call.GetReturnText()
+}
@@ -0,0 +1,595 @@
+package debug
+
+import (
+  "debug/elf"
+  "io/ioutil"
+  "os"
+  "sort"
+  "sync/atomic"
+  "strconv"
+  "strings"
+  "syscall"
+
+  "jelf/core/err"
+)
+
+const (
+  ptraceOptions = syscall.PTRACE_O_TRACESYSGOOD | syscall.PTRACE_O_TRACECLONE | syscall.PTRACE_O_TRACEFORK |
+    syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACEVFORKDONE | syscall.PTRACE_O_TRACEEXEC
+)
+
+// the state of a thread while another one is the current of the debugger
+type Thread struct {
+  Tid int
+  Signal syscall.Signal // delivered when the thread is resumed
+  InSyscall bool
+  Syscall *Syscall
+  Stopped bool
+  Reported bool // stopped at a breakpoint hit, which is stepped over when resumed
+
+  pending *syscall.WaitStatus // a stop received while stopping the threads, reported later
+  starting bool // the first stop of a new thread is not received yet
+  stopping bool // the SIGSTOP sent by the debugger is not received yet
+}
+
+func (p *Debugger) GetThread(tid int) *Thread {
+  for _, thread := range p.Threads {
+    if thread.Tid == tid {
+      return thread
+    }
+  }
+
+  return nil
+}
+
+func (p *Debugger) removeThread(tid int) {
+  for i, thread := range p.Threads {
+    if thread.Tid == tid {
+      p.Threads = append(p.Threads[:i], p.Threads[i + 1:]...)
+
+      break
+    }
+  }
+
+  if p.Tid == tid {
+    p.Tid = p.Pid
+    p.load()
+  }
+}
+
+// keeps the state of the current thread, or takes it back
+func (p *Debugger) save() {
+  if thread := p.GetThread(p.Tid); thread != nil {
+    thread.Signal = p.Signal
+    thread.InSyscall = p.InSyscall
+    thread.Syscall = p.Syscall
+  }
+}
+
+func (p *Debugger) load() {
+  if thread := p.GetThread(p.Tid); thread != nil {
+    p.Signal = thread.Signal
+    p.InSyscall = thread.InSyscall
+    p.Syscall = thread.Syscall
+  }
+}
+
+// makes the thread the current one, the one of the registers and the memory
+func (p *Debugger) SetThread(tid int) error {
+  if p.Running == false {
+    return err.NotRunning
+  }
+
+  if p.GetThread(tid) == nil {
+    return err.ThreadNotFound
+  }
+
+  p.save()
+
+  p.Tid = tid
+  p.Hit = nil
+  p.Watch = nil
+  p.Exec = false
+
+  p.load()
+
+  return nil
+}
+
+func (p *Debugger) GetThreadName(tid int) string {
+  data, _ := ioutil.ReadFile("/proc/" + strconv.Itoa(p.Pid) + "/task/" + strconv.Itoa(tid) + "/comm")
+
+  return strings.TrimSpace(string(data))
+}
+
+func (p *Debugger) GetThreadPC(tid int) (uint64, error) {
+  current := p.Tid
+
+  p.Tid = tid
+
+  pc, e := p.GetPC()
+
+  p.Tid = current
+
+  return pc, e
+}
+
+// attaches the other threads of the process, until no new one appears
+func (p *Debugger) attachThreads() {
+  for {
+    entries, e := ioutil.ReadDir("/proc/" + strconv.Itoa(p.Pid) + "/task")
+
+    if e != nil {
+      return
+    }
+
+    found := false
+
+    for _, entry := range entries {
+      tid, e := strconv.Atoi(entry.Name())
+
+      if e != nil || p.GetThread(tid) != nil || syscall.PtraceAttach(tid) != nil {
+        continue
+      }
+
+      var status syscall.WaitStatus
+
+      if _, e := syscall.Wait4(tid, &status, syscall.WALL, nil); e != nil || status.Stopped() == false {
+        continue
+      }
+
+      syscall.PtraceSetOptions(tid, ptraceOptions)
+
+      p.Threads = append(p.Threads, &Thread{Tid: tid, Stopped: true})
+
+      found = true
+    }
+
+    if found == false {
+      break
+    }
+  }
+
+  sort.Slice(p.Threads, func(i, j int) bool {
+    return p.Threads[i].Tid < p.Threads[j].Tid
+  })
+}
+
+// resumes the threads until the next stop to report, making its thread the
+// current one. The other threads are stopped again before returning, except
+// for the single steps where only the current thread runs
+func (p *Debugger) resume(request int) error {
+  alone := request == syscall.PTRACE_SINGLESTEP
+
+  // the syscall completes without a stop when not resumed by PTRACE_SYSCALL
+  if request != syscall.PTRACE_SYSCALL {
+    p.InSyscall = false
+  }
+
+  p.Hit = nil
+  p.Watch = nil
+  p.Exec = false
+
+  // the input of jelf goes to the pty while the process runs
+  if alone == false {
+    atomic.StoreInt32(&p.forwarding, 1)
+
+    defer atomic.StoreInt32(&p.forwarding, 0)
+  }
+
+  p.save()
+
+  if alone == false {
+    for _, thread := range p.Threads {
+      if thread.Tid != p.Tid && thread.Reported && thread.pending == nil {
+        p.stepOver(thread)
+      }
+    }
+  }
+
+  if current := p.GetThread(p.Tid); current != nil && (alone || p.getPending() == nil) {
+    if e := p.resumeThread(current, request); e != nil {
+      return e
+    }
+  }
+
+  for p.Running {
+    var status syscall.WaitStatus
+    var tid int
+
+    if thread := p.getPending(); thread != nil && alone == false {
+      tid, status = thread.Tid, *thread.pending
+
+      thread.pending = nil
+    } else {
+      for _, thread := range p.Threads {
+        if thread.Stopped && thread.pending == nil && (alone == false || thread.Tid == p.Tid) {
+          p.resumeThread(thread, request)
+        }
+      }
+
+      var e error
+
+      if tid, e = syscall.Wait4(-1, &status, syscall.WALL, nil); e != nil {
+        return e
+      }
+    }
+
+    if p.handle(tid, status) {
+      break
+    }
+  }
+
+  if p.Running && alone == false {
+    p.stopThreads()
+  }
+
+  return nil
+}
+
+func (p *Debugger) getPending() *Thread {
+  for _, thread := range p.Threads {
+    if thread.pending != nil {
+      return thread
+    }
+  }
+
+  return nil
+}
+
+func (p *Debugger) resumeThread(thread *Thread, request int) error {
+  if e := ptraceRequest(request, thread.Tid, 0, uintptr(thread.Signal)); e != nil {
+    return e
+  }
+
+  thread.Signal = 0
+  thread.Stopped = false
+  thread.Reported = false
+
+  if request != syscall.PTRACE_SYSCALL {
+    thread.InSyscall = false
+  }
+
+  return nil
+}
+
+// moves a thread which reported a breakpoint over it, so it is not hit again
+func (p *Debugger) stepOver(thread *Thread) {
+  current := p.Tid
+
+  p.Tid = thread.Tid
+
+  if pc, e := p.GetPC(); e == nil {
+    if breakpoint := p.GetBreakpointAt(pc); breakpoint != nil {
+      p.Remove(breakpoint)
+
+      var status syscall.WaitStatus
+
+      if ptraceRequest(syscall.PTRACE_SINGLESTEP, thread.Tid, 0, 0) == nil {
+        if _, e := syscall.Wait4(thread.Tid, &status, syscall.WALL, nil); e == nil && status.StopSignal() != syscall.SIGTRAP {
+          thread.pending = &status
+        }
+      }
+
+      p.Insert(breakpoint)
+    }
+  }
+
+  thread.Reported = false
+
+  p.Tid = current
+}
+
+// stops the running threads, keeping the stops which are not the SIGSTOP
+// of the debugger for later. The breakpoints hit by them are just rewound
+// to be hit again when resumed
+func (p *Debugger) stopThreads() {
+  var stopping []*Thread
+
+  // a SIGSTOP still on the way is not sent again, a second one would be
+  // queued when the first one is already taken by the thread
+  for _, thread := range p.Threads {
+    if thread.Stopped == false && thread.starting == false && thread.stopping == false {
+      syscall.Tgkill(p.Pid, thread.Tid, syscall.SIGSTOP)
+
+      thread.stopping = true
+    }
+
+    if thread.Stopped == false {
+      stopping = append(stopping, thread)
+    }
+  }
+
+  for _, thread := range stopping {
+    var status syscall.WaitStatus
+
+    if _, e := syscall.Wait4(thread.Tid, &status, syscall.WALL, nil); e != nil || status.Stopped() == false {
+      p.removeThread(thread.Tid)
+
+      continue
+    }
+
+    thread.Stopped = true
+
+    if status.StopSignal() == syscall.SIGSTOP && (thread.stopping || thread.starting) {
+      if thread.starting {
+        p.startThread(thread)
+      }
+
+      thread.stopping = false
+
+      continue
+    }
+
+    if status.StopSignal() == syscall.SIGTRAP && status.TrapCause() == 0 && p.rewind(thread) {
+      continue
+    }
+
+    thread.pending = &status
+  }
+}
+
+// moves the pc of the thread back to the breakpoint it has just hit
+func (p *Debugger) rewind(thread *Thread) bool {
+  current := p.Tid
+
+  p.Tid = thread.Tid
+
+  defer func() {
+    p.Tid = current
+  }()
+
+  pc, e := p.GetPC()
+
+  if e != nil {
+    return false
+  }
+
+  if breakpoint := p.GetBreakpointAt(pc - 1); breakpoint != nil {
+    return p.SetPC(pc - 1) == nil
+  }
+
+  return false
+}
+
+// the debug registers are not inherited by the new threads
+func (p *Debugger) startThread(thread *Thread) {
+  thread.starting = false
+
+  p.updateDebugRegisters(thread.Tid)
+}
+
+// handles the stop of a thread, telling if it must be reported
+func (p *Debugger) handle(tid int, status syscall.WaitStatus) bool {
+  thread := p.GetThread(tid)
+
+  if thread == nil {
+    // a new thread or process can stop before the event of its creation
+    if status.Stopped() {
+      p.early[tid] = true
+    }
+
+    return false
+  }
+
+  if status.Exited() || status.Signaled() {
+    if tid != p.Pid {
+      p.removeThread(tid)
+
+      return false
+    }
+
+    p.Tid = tid
+    p.setStatus(status)
+
+    return true
+  }
+
+  thread.Stopped = true
+
+  if status.StopSignal() == syscall.SIGTRAP {
+    switch status.TrapCause() {
+      case syscall.PTRACE_EVENT_CLONE:
+        p.clone(thread)
+
+        return false
+      case syscall.PTRACE_EVENT_FORK, syscall.PTRACE_EVENT_VFORK:
+        p.fork(thread, status.TrapCause() == syscall.PTRACE_EVENT_VFORK)
+
+        return false
+      case syscall.PTRACE_EVENT_VFORK_DONE:
+        if p.FollowFork != "child" {
+          for _, breakpoint := range p.Breakpoints {
+            p.Insert(breakpoint)
+          }
+        }
+
+        return false
+      case syscall.PTRACE_EVENT_EXEC:
+        p.exec()
+        p.setStatus(status)
+
+        return true
+    }
+  }
+
+  if status.StopSignal() == syscall.SIGSTOP && (thread.starting || thread.stopping) {
+    if thread.starting {
+      p.startThread(thread)
+    }
+
+    thread.stopping = false
+
+    return false
+  }
+
+  // signals of the normal operation are passed without a stop
+  if status.StopSignal() == syscall.SIGCHLD || status.StopSignal() == syscall.SIGWINCH {
+    thread.Signal = status.StopSignal()
+
+    return false
+  }
+
+  // the state of the threads was kept when they were resumed
+  p.Tid = tid
+
+  p.load()
+
+  p.setStatus(status)
+
+  return true
+}
+
+func (p *Debugger) getEventMessage(tid int) int {
+  message, _ := syscall.PtraceGetEventMsg(tid)
+
+  return int(message)
+}
+
+func (p *Debugger) clone(parent *Thread) {
+  tid := p.getEventMessage(parent.Tid)
+
+  thread := &Thread{
+    Tid: tid, starting: true}
+
+  p.Threads = append(p.Threads, thread)
+
+  if p.early[tid] {
+    delete(p.early, tid)
+
+    thread.Stopped = true
+
+    p.startThread(thread)
+  }
+}
+
+// writes the original bytes of the breakpoints in the memory of another process
+func (p *Debugger) restoreMemory(pid int) {
+  for _, breakpoint := range p.Breakpoints {
+    if breakpoint.Inserted {
+      syscall.PtracePokeData(pid, uintptr(breakpoint.Location), []byte{breakpoint.Original})
+    }
+  }
+}
+
+// keeps tracing the parent or the child, according to FollowFork. The memory
+// of a vfork is shared until the child executes a program or exits
+func (p *Debugger) fork(parent *Thread, vfork bool) {
+  child := p.getEventMessage(parent.Tid)
+
+  // the child starts with a SIGSTOP
+  if p.early[child] {
+    delete(p.early, child)
+  } else {
+    var status syscall.WaitStatus
+
+    syscall.Wait4(child, &status, syscall.WALL, nil)
+  }
+
+  if p.FollowFork != "child" {
+    // the breakpoints of the shared memory are removed until the vfork is done
+    if vfork {
+      for _, breakpoint := range p.Breakpoints {
+        p.Remove(breakpoint)
+      }
+    } else {
+      p.restoreMemory(child)
+    }
+
+    ptraceRequest(syscall.PTRACE_DETACH, child, 0, 0)
+
+    return
+  }
+
+  p.Tid = parent.Tid
+
+  p.stopThreads()
+
+  if vfork {
+    for _, breakpoint := range p.Breakpoints {
+      p.Remove(breakpoint)
+    }
+  } else {
+    p.restoreMemory(p.Pid)
+  }
+
+  watchpoints := p.Watchpoints
+
+  p.Watchpoints = nil
+
+  for _, thread := range p.Threads {
+    p.updateDebugRegisters(thread.Tid)
+
+    ptraceRequest(syscall.PTRACE_DETACH, thread.Tid, 0, uintptr(thread.Signal))
+  }
+
+  p.Watchpoints = watchpoints
+
+  p.Pid = child
+  p.Tid = child
+  p.Threads = []*Thread{&Thread{Tid: child, Stopped: true}}
+
+  p.load()
+
+  p.updateDebugRegisters(child)
+}
+
+// the threads of the process are gone with the memory of the old program,
+// the breakpoints are inserted again when it is the same executable
+func (p *Debugger) exec() {
+  p.Tid = p.Pid
+  p.Threads = []*Thread{&Thread{Tid: p.Pid, Stopped: true}}
+  p.Exec = true
+  p.Signal = 0
+  p.InSyscall = false
+  p.Syscall = nil
+  p.Libraries = nil
+
+  for _, breakpoint := range p.Breakpoints {
+    breakpoint.Inserted = false
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    watchpoint.Inserted = false
+  }
+
+  path, e := os.Readlink("/proc/" + strconv.Itoa(p.Pid) + "/exe")
+
+  if e != nil {
+    return
+  }
+
+  same := false
+
+  if old, e := os.Stat(p.Path); e == nil {
+    if current, e := os.Stat(path); e == nil {
+      same = os.SameFile(old, current)
+    }
+  }
+
+  if same == false {
+    file, e := elf.Open(path)
+
+    if e != nil {
+      return
+    }
+
+    p.Path = path
+    p.File = file
+    p.frames = nil
+    p.Breakpoints = nil
+    p.Watchpoints = nil
+  }
+
+  p.Base, p.Bias = p.GetLoadBase()
+
+  for _, breakpoint := range p.Breakpoints {
+    p.Insert(breakpoint)
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    p.InsertWatchpoint(watchpoint)
+  }
+
+  p.WatchLibraries()
+}
@@ -0,0 +1,275 @@
+package debug
+
+import (
+  "encoding/binary"
+  "syscall"
+  "unsafe"
+
+  "golang.org/x/arch/x86/x86asm"
+
+  "jelf/core/err"
+)
+
+const (
+  debugRegisterOffset = 848 // offsetof(struct user, u_debugreg)
+  debugStatus = 6
+  debugControl = 7
+  maxWatchpoints = 4
+)
+
+// the address is the one of the file like for the breakpoints, the kind is r, w or rw
+type Watchpoint struct {
+  Id int `json:"id"`
+  Address uint64 `json:"address"`
+  Length uint64 `json:"length"`
+  Kind string `json:"kind"`
+  Hits int `json:"hits"`
+  Old uint64 `json:"old"`
+  Value uint64 `json:"value"`
+  Slot int `json:"-"` // the debug register DR0-DR3
+  Inserted bool `json:"-"`
+}
+
+func (p *Debugger) GetWatchpointById(id int) *Watchpoint {
+  for _, watchpoint := range p.Watchpoints {
+    if watchpoint.Id == id {
+      return watchpoint
+    }
+  }
+
+  return nil
+}
+
+// creates a watchpoint in a free debug register, inserted now if the process is running or when it starts
+func (p *Debugger) AddWatchpoint(addr, length uint64, kind string) (*Watchpoint, error) {
+  if kind != "r" && kind != "w" && kind != "rw" {
+    return nil, err.InvalidOption
+  }
+
+  // the cpu only watches aligned ranges of 1, 2, 4 or 8 bytes
+  if (length != 1 && length != 2 && length != 4 && length != 8) || addr % length != 0 {
+    return nil, err.InvalidWatchpoint
+  }
+
+  if len(p.Watchpoints) >= maxWatchpoints {
+    return nil, err.TooManyWatchpoints
+  }
+
+  used := make([]bool, maxWatchpoints)
+
+  for _, watchpoint := range p.Watchpoints {
+    used[watchpoint.Slot] = true
+  }
+
+  slot := 0
+
+  for used[slot] {
+    slot++
+  }
+
+  watchpoint := &Watchpoint{
+    Id: p.nextId, Address: addr, Length: length, Kind: kind, Slot: slot}
+
+  if p.Running {
+    if e := p.InsertWatchpoint(watchpoint); e != nil {
+      return nil, e
+    }
+  }
+
+  p.nextId = p.nextId + 1
+  p.Watchpoints = append(p.Watchpoints, watchpoint)
+
+  return watchpoint, nil
+}
+
+func (p *Debugger) DeleteWatchpoint(watchpoint *Watchpoint) error {
+  for i, w := range p.Watchpoints {
+    if w == watchpoint {
+      if p.Running {
+        p.RemoveWatchpoint(watchpoint)
+      }
+
+      p.Watchpoints = append(p.Watchpoints[:i], p.Watchpoints[i + 1:]...)
+
+      return nil
+    }
+  }
+
+  return err.BreakpointNotFound
+}
+
+func (p *Debugger) GetDebugRegister(index int) (uint64, error) {
+  var value uint64
+
+  e := p.ptrace(syscall.PTRACE_PEEKUSR, uintptr(debugRegisterOffset + index*8), uintptr(unsafe.Pointer(&value)))
+
+  return value, e
+}
+
+func setDebugRegister(tid, index int, value uint64) error {
+  return ptraceRequest(syscall.PTRACE_POKEUSR, tid, uintptr(debugRegisterOffset + index*8), uintptr(value))
+}
+
+// writes the inserted watchpoints in the debug registers of the thread, which
+// are not shared with the other ones
+func (p *Debugger) updateDebugRegisters(tid int) error {
+  var control uint64
+
+  for _, watchpoint := range p.Watchpoints {
+    if watchpoint.Inserted == false {
+      continue
+    }
+
+    if e := setDebugRegister(tid, watchpoint.Slot, watchpoint.Address + p.Bias); e != nil {
+      return e
+    }
+
+    // there are no read only watchpoints, the reads are told apart when they trap
+    var rw uint64 = 0x3
+
+    if watchpoint.Kind == "w" {
+      rw = 0x1
+    }
+
+    // the encoding of the length is 1: 00, 2: 01, 8: 10, 4: 11
+    size := map[uint64]uint64{1: 0x0, 2: 0x1, 8: 0x2, 4: 0x3}[watchpoint.Length]
+
+    shift := uint(16 + watchpoint.Slot*4)
+
+    control = control | (rw << shift) | (size << (shift + 2)) | (1 << uint(watchpoint.Slot*2))
+  }
+
+  return setDebugRegister(tid, debugControl, control)
+}
+
+// reads the watched bytes as a little endian number
+func (p *Debugger) readWatched(watchpoint *Watchpoint) (uint64, error) {
+  data, e := p.ReadMemory(watchpoint.Address + p.Bias, watchpoint.Length)
+
+  if e != nil || uint64(len(data)) < watchpoint.Length {
+    return 0, err.AddressNotMapped
+  }
+
+  buffer := make([]byte, 8)
+
+  copy(buffer, data)
+
+  return binary.LittleEndian.Uint64(buffer), nil
+}
+
+// enables the watchpoint in the debug registers of every thread
+func (p *Debugger) InsertWatchpoint(watchpoint *Watchpoint) error {
+  if watchpoint.Inserted {
+    return nil
+  }
+
+  watchpoint.Inserted = true
+
+  for _, thread := range p.Threads {
+    if e := p.updateDebugRegisters(thread.Tid); e != nil {
+      watchpoint.Inserted = false
+
+      p.updateDebugRegisters(thread.Tid)
+
+      return e
+    }
+  }
+
+  watchpoint.Value, _ = p.readWatched(watchpoint)
+
+  return nil
+}
+
+func (p *Debugger) RemoveWatchpoint(watchpoint *Watchpoint) error {
+  if watchpoint.Inserted == false {
+    return nil
+  }
+
+  watchpoint.Inserted = false
+
+  var e error
+
+  for _, thread := range p.Threads {
+    if failure := p.updateDebugRegisters(thread.Tid); failure != nil {
+      e = failure
+    }
+  }
+
+  return e
+}
+
+// finds the watchpoint which trapped in DR6, updating its values. Data
+// watchpoints trap after the instruction, so the pc is the next one
+func (p *Debugger) checkWatchpoints() {
+  inserted := false
+
+  for _, watchpoint := range p.Watchpoints {
+    inserted = inserted || watchpoint.Inserted
+  }
+
+  if inserted == false {
+    return
+  }
+
+  status, e := p.GetDebugRegister(debugStatus)
+
+  if e != nil {
+    return
+  }
+
+  for _, watchpoint := range p.Watchpoints {
+    if watchpoint.Inserted && status & (1 << uint(watchpoint.Slot)) != 0 {
+      watchpoint.Old = watchpoint.Value
+      watchpoint.Value, _ = p.readWatched(watchpoint)
+      watchpoint.Hits = watchpoint.Hits + 1
+
+      p.Watch = watchpoint
+    }
+  }
+
+  setDebugRegister(p.Tid, debugStatus, 0) // the cpu never clears it
+}
+
+// finds the instruction ending at the pc, decoding from the start of the
+// function when known, or else trying the longest instruction before it
+func (p *Debugger) GetPreviousInstruction(pc, start uint64) (uint64, error) {
+  mode := 64
+
+  if p.GetWordSize() == 4 {
+    mode = 32
+  }
+
+  if start != 0 && start < pc && pc - start <= 0x10000 {
+    code, e := p.ReadMemory(start, pc - start)
+
+    if e == nil {
+      for offset := 0; offset < len(code); {
+        ins, e := x86asm.Decode(code[offset:], mode)
+
+        if e != nil {
+          break
+        }
+
+        if offset + ins.Len == len(code) {
+          return start + uint64(offset), nil
+        }
+
+        offset = offset + ins.Len
+      }
+    }
+  }
+
+  code, e := p.ReadMemory(pc - 15, 15)
+
+  if e != nil || len(code) < 15 {
+    return 0, err.AddressNotMapped
+  }
+
+  for n := 15; n > 0; n-- {
+    if ins, e := x86asm.Decode(code[15 - n:], mode); e == nil && ins.Len == n {
+      return pc - uint64(n), nil
+    }
+  }
+
+  return 0, err.TruncatedInstruction
+}
@@ -10,4 +10,29 @@ var (
   NoStringFound = errors.New("No string found")
   NoSymbolFound = errors.New("No symbol found")
   AddressNotFound = errors.New("Address not found")
+  AddressNotMapped = errors.New("Address not mapped")
+  ZeroFill = errors.New("Address is zero-fill")
+  DynamicNotFound = errors.New("Dynamic entry not found")
+  CommandNotFound = errors.New("Command not found")
+  CommandFailed = errors.New("Command failed")
+  PolicyFailed = errors.New("Policy failed")
+  InvalidOption = errors.New("Invalid option")
+  Quit = errors.New("Quit")
+  UnsupportedArchitecture = errors.New("Unsupported architecture")
+  InvalidMode = errors.New("Invalid decoder mode")
+  TruncatedInstruction = errors.New("Truncated instruction")
+  NotRunning = errors.New("Process is not running")
+  AlreadyRunning = errors.New("Process is already running")
+  BreakpointNotFound = errors.New("Breakpoint not found")
+  RegisterNotFound = errors.New("Register not found")
+  InvalidWatchpoint = errors.New("Watchpoints must be aligned ranges of 1, 2, 4 or 8 bytes")
+  ThreadNotFound = errors.New("Thread not found")
+  LibraryNotLoaded = errors.New("Library not loaded")
+  TooManyWatchpoints = errors.New("No debug register available, up to 4 watchpoints")
+  LineNotFound = errors.New("No line information")
+  SourceNotFound = errors.New("Source file not found")
+  UnsupportedExpression = errors.New("Unsupported location expression")
+  DwarfNotFound = errors.New("No dwarf information")
+  TypeNotFound = errors.New("Type not found")
+  FunctionNotFound = errors.New("No function at the address")
 )
@@ -10,515 +10,841 @@ import (
 
   "jelf/core/state"
   "jelf/core/err"
+  "jelf/core/asm"
+  "jelf/core/misc"
 
-	"golang.org/x/arch/x86/x86asm"
 )
 
 type Information struct {
   *state.State
 }
 
-func (p *Information) ShowInformation() {
-  fmt.Print("Class:[", p.File.Class.String(), "]: ")
+type Header struct {
+  Class string `json:"class"`
+  ClassDescription string `json:"class_description"`
+  Data string `json:"data"`
+  DataDescription string `json:"data_description"`
+  OSABI string `json:"osabi"`
+  OSABIDescription string `json:"osabi_description"`
+  Type string `json:"type"`
+  TypeDescription string `json:"type_description"`
+  Machine string `json:"machine"`
+  MachineDescription string `json:"machine_description"`
+  Entry uint64 `json:"entry"`
+}
 
+func (p *Information) GetClassDescription() string {
   if (p.File.Class == elf.ELFCLASS32) {
-    fmt.Println("32 bits")
+    return "32 bits"
   } else if (p.File.Class == elf.ELFCLASS64) {
-    fmt.Println("64 bits")
+    return "64 bits"
   } else {
-    fmt.Println("Unknown")
+    return "Unknown"
   }
+}
 
-  fmt.Print("Endianess:[", p.File.Data.String(), "]: ")
-
+func (p *Information) GetDataDescription() string {
   if (p.File.Data == elf.ELFDATA2LSB) {
-    fmt.Println("Little Endian")
+    return "Little Endian"
   } else if (p.File.Data == elf.ELFDATA2MSB) {
-    fmt.Println("Big Endian")
+    return "Big Endian"
   } else {
-    fmt.Println("Unknown")
+    return "Unknown"
   }
+}
 
-  fmt.Print("System ABI:[", p.File.OSABI.String(), "]: ")
-
+func (p *Information) GetOSABIDescription() string {
   if (p.File.OSABI == elf.ELFOSABI_HPUX) {
-    fmt.Println("HP-UX operating system")
+    return "HP-UX operating system"
   } else if (p.File.OSABI == elf.ELFOSABI_NETBSD) {
-    fmt.Println("NetBSD")
+    return "NetBSD"
   } else if (p.File.OSABI == elf.ELFOSABI_LINUX) {
-    fmt.Println("GNU/Linux")
+    return "GNU/Linux"
   } else if (p.File.OSABI == elf.ELFOSABI_HURD) {
-    fmt.Println("GNU/Hurd")
+    return "GNU/Hurd"
   } else if (p.File.OSABI == elf.ELFOSABI_86OPEN) {
-    fmt.Println("86Open common IA32 ABI")
+    return "86Open common IA32 ABI"
   } else if (p.File.OSABI == elf.ELFOSABI_SOLARIS) {
-    fmt.Println("Solaris")
+    return "Solaris"
   } else if (p.File.OSABI == elf.ELFOSABI_AIX) {
-    fmt.Println("AIX")
+    return "AIX"
   } else if (p.File.OSABI == elf.ELFOSABI_IRIX) {
-    fmt.Println("IRIX")
+    return "IRIX"
   } else if (p.File.OSABI == elf.ELFOSABI_FREEBSD) {
-    fmt.Println("FreeBSD")
+    return "FreeBSD"
   } else if (p.File.OSABI == elf.ELFOSABI_TRU64) {
-    fmt.Println("TRU64 UNIX")
+    return "TRU64 UNIX"
   } else if (p.File.OSABI == elf.ELFOSABI_MODESTO) {
-    fmt.Println("Novell Modesto")
+    return "Novell Modesto"
   } else if (p.File.OSABI == elf.ELFOSABI_OPENBSD) {
-    fmt.Println("OpenBSD")
+    return "OpenBSD"
   } else if (p.File.OSABI == elf.ELFOSABI_OPENVMS) {
-    fmt.Println("Open VMS")
+    return "Open VMS"
   } else if (p.File.OSABI == elf.ELFOSABI_NSK) {
-    fmt.Println("HP Non-Stop Kernel")
+    return "HP Non-Stop Kernel"
   } else if (p.File.OSABI == elf.ELFOSABI_AROS) {
-    fmt.Println("Amiga Research OS")
+    return "Amiga Research OS"
   } else if (p.File.OSABI == elf.ELFOSABI_FENIXOS) {
-    fmt.Println("The FenixOS highly scalable multi-core OS")
+    return "The FenixOS highly scalable multi-core OS"
   } else if (p.File.OSABI == elf.ELFOSABI_CLOUDABI) {
-    fmt.Println("Nuxi CloudABI")
+    return "Nuxi CloudABI"
   } else if (p.File.OSABI == elf.ELFOSABI_ARM) {
-    fmt.Println("ARM")
+    return "ARM"
   } else if (p.File.OSABI == elf.ELFOSABI_STANDALONE) {
-    fmt.Println("Standalone (embedded) application")
+    return "Standalone (embedded) application"
   } else {
-    fmt.Println("Unknown")
+    return "Unknown"
   }
+}
 
-  fmt.Print("Type:[", p.File.Type.String(), "]: ")
-
+func (p *Information) GetTypeDescription() string {
   if (p.File.Type == elf.ET_REL) {
-    fmt.Println("Relocatable")
+    return "Relocatable"
   } else if (p.File.Type == elf.ET_EXEC) {
-    fmt.Println("Executable")
+    return "Executable"
   } else if (p.File.Type == elf.ET_DYN) {
-    fmt.Println("Shared object")
+    return "Shared object"
   } else if (p.File.Type == elf.ET_CORE) {
-    fmt.Println("Core file")
+    return "Core file"
   } else if (p.File.Type == elf.ET_LOOS) {
-    fmt.Println("First operating system specific")
+    return "First operating system specific"
   } else if (p.File.Type == elf.ET_HIOS) {
-    fmt.Println("Last operating system-specific")
+    return "Last operating system-specific"
   } else if (p.File.Type == elf.ET_LOPROC) {
-    fmt.Println("First processor-specific")
+    return "First processor-specific"
   } else if (p.File.Type == elf.ET_HIPROC) {
-    fmt.Println("Last processor-specific")
+    return "Last processor-specific"
   } else {
-    fmt.Println("Unknown")
+    return "Unknown"
   }
+}
 
-  fmt.Print("Machine:[", p.File.Machine.String(), "]: ")
-
+func (p *Information) GetMachineDescription() string {
   if (p.File.Machine == elf.EM_M32) {
-    fmt.Println("AT&T WE32100")
+    return "AT&T WE32100"
   } else if (p.File.Machine == elf.EM_SPARC) {
-    fmt.Println("Sun SPARC")
+    return "Sun SPARC"
   } else if (p.File.Machine == elf.EM_386) {
-    fmt.Println("Intel i386")
+    return "Intel i386"
   } else if (p.File.Machine == elf.EM_68K) {
-    fmt.Println("Motorola 68000")
+    return "Motorola 68000"
   } else if (p.File.Machine == elf.EM_88K) {
-    fmt.Println("Motorola 88000")
+    return "Motorola 88000"
   } else if (p.File.Machine == elf.EM_860) {
-    fmt.Println("Intel i860")
+    return "Intel i860"
   } else if (p.File.Machine == elf.EM_MIPS) {
-    fmt.Println("MIPS R3000 Big-Endian only")
+    return "MIPS R3000 Big-Endian only"
   } else if (p.File.Machine == elf.EM_S370) {
-    fmt.Println("IBM System/370")
+    return "IBM System/370"
   } else if (p.File.Machine == elf.EM_MIPS_RS3_LE) {
-    fmt.Println("MIPS R3000 Little-Endian")
+    return "MIPS R3000 Little-Endian"
   } else if (p.File.Machine == elf.EM_PARISC) {
-    fmt.Println("HP PA-RISC")
+    return "HP PA-RISC"
   } else if (p.File.Machine == elf.EM_VPP500) {
-    fmt.Println("Fujitsu VPP500")
+    return "Fujitsu VPP500"
   } else if (p.File.Machine == elf.EM_SPARC32PLUS) {
-    fmt.Println("SPARC v8plus")
+    return "SPARC v8plus"
   } else if (p.File.Machine == elf.EM_960) {
-    fmt.Println("Intel 80960")
+    return "Intel 80960"
   } else if (p.File.Machine == elf.EM_PPC) {
-    fmt.Println("PowerPC 32-bit")
+    return "PowerPC 32-bit"
   } else if (p.File.Machine == elf.EM_PPC64) {
-    fmt.Println("PowerPC 64-bit")
+    return "PowerPC 64-bit"
   } else if (p.File.Machine == elf.EM_S390) {
-    fmt.Println("IBM System/390")
+    return "IBM System/390"
   } else if (p.File.Machine == elf.EM_V800) {
-    fmt.Println("NEC V800")
+    return "NEC V800"
   } else if (p.File.Machine == elf.EM_FR20) {
-    fmt.Println("Fujitsu FR20")
+    return "Fujitsu FR20"
   } else if (p.File.Machine == elf.EM_RH32) {
-    fmt.Println("TRW RH-32")
+    return "TRW RH-32"
   } else if (p.File.Machine == elf.EM_RCE) {
-    fmt.Println("Motorola RCE")
+    return "Motorola RCE"
   } else if (p.File.Machine == elf.EM_ARM) {
-    fmt.Println("ARM")
+    return "ARM"
   } else if (p.File.Machine == elf.EM_SH) {
-    fmt.Println("Hitachi SH")
+    return "Hitachi SH"
   } else if (p.File.Machine == elf.EM_SPARCV9) {
-    fmt.Println("SPARC v9 64-bit")
+    return "SPARC v9 64-bit"
   } else if (p.File.Machine == elf.EM_TRICORE) {
-    fmt.Println("Siemens TriCore embedded processor")
+    return "Siemens TriCore embedded processor"
   } else if (p.File.Machine == elf.EM_ARC) {
-    fmt.Println("Argonaut RISC Core")
+    return "Argonaut RISC Core"
   } else if (p.File.Machine == elf.EM_H8_300) {
-    fmt.Println("Hitachi H8/300")
+    return "Hitachi H8/300"
   } else if (p.File.Machine == elf.EM_H8_300H) {
-    fmt.Println("Hitachi H8/300H")
+    return "Hitachi H8/300H"
   } else if (p.File.Machine == elf.EM_H8S) {
-    fmt.Println("Hitachi H8S")
+    return "Hitachi H8S"
   } else if (p.File.Machine == elf.EM_H8_500) {
-    fmt.Println("Hitachi H8/500")
+    return "Hitachi H8/500"
   } else if (p.File.Machine == elf.EM_IA_64) {
-    fmt.Println("Intel IA-64 Processor")
+    return "Intel IA-64 Processor"
   } else if (p.File.Machine == elf.EM_MIPS_X) {
-    fmt.Println("Stanford MIPS-X")
+    return "Stanford MIPS-X"
   } else if (p.File.Machine == elf.EM_COLDFIRE) {
-    fmt.Println("Motorola ColdFire")
+    return "Motorola ColdFire"
   } else if (p.File.Machine == elf.EM_68HC12) {
-    fmt.Println("Motorola M68HC12")
+    return "Motorola M68HC12"
   } else if (p.File.Machine == elf.EM_MMA) {
-    fmt.Println("Fujitsu MMA")
+    return "Fujitsu MMA"
   } else if (p.File.Machine == elf.EM_PCP) {
-    fmt.Println("Siemens PCP")
+    return "Siemens PCP"
   } else if (p.File.Machine == elf.EM_NCPU) {
-    fmt.Println("Sony nCPU")
+    return "Sony nCPU"
   } else if (p.File.Machine == elf.EM_NDR1) {
-    fmt.Println("Denso NDR1 microprocessor")
+    return "Denso NDR1 microprocessor"
   } else if (p.File.Machine == elf.EM_STARCORE) {
-    fmt.Println("Motorola Star*Core processor")
+    return "Motorola Star*Core processor"
   } else if (p.File.Machine == elf.EM_ME16) {
-    fmt.Println("Toyota ME16 processor")
+    return "Toyota ME16 processor"
   } else if (p.File.Machine == elf.EM_ST100) {
-    fmt.Println("STMicroelectronics ST100 processor")
+    return "STMicroelectronics ST100 processor"
   } else if (p.File.Machine == elf.EM_TINYJ) {
-    fmt.Println("Advanced Logic Corp. TinyJ processor")
+    return "Advanced Logic Corp. TinyJ processor"
   } else if (p.File.Machine == elf.EM_X86_64) {
-    fmt.Println("Advanced Micro Devices x86-64")
+    return "Advanced Micro Devices x86-64"
   } else if (p.File.Machine == elf.EM_PDSP) {
-    fmt.Println("Sony DSP Processor")
+    return "Sony DSP Processor"
   } else if (p.File.Machine == elf.EM_PDP10) {
-    fmt.Println("Digital Equipment Corp. PDP-10")
+    return "Digital Equipment Corp. PDP-10"
   } else if (p.File.Machine == elf.EM_PDP11) {
-    fmt.Println("Digital Equipment Corp. PDP-11")
+    return "Digital Equipment Corp. PDP-11"
   } else if (p.File.Machine == elf.EM_FX66) {
-    fmt.Println("Siemens FX66 microcontroller")
+    return "Siemens FX66 microcontroller"
   } else if (p.File.Machine == elf.EM_ST9PLUS) {
-    fmt.Println("STMicroelectronics ST9+ 8/16 bit microcontroller")
+    return "STMicroelectronics ST9+ 8/16 bit microcontroller"
   } else if (p.File.Machine == elf.EM_ST7) {
-    fmt.Println("STMicroelectronics ST7 8-bit microcontroller")
+    return "STMicroelectronics ST7 8-bit microcontroller"
   } else if (p.File.Machine == elf.EM_68HC16) {
-    fmt.Println("Motorola MC68HC16 Microcontroller")
+    return "Motorola MC68HC16 Microcontroller"
   } else if (p.File.Machine == elf.EM_68HC11) {
-    fmt.Println("Motorola MC68HC11 Microcontroller")
+    return "Motorola MC68HC11 Microcontroller"
   } else if (p.File.Machine == elf.EM_68HC08) {
-    fmt.Println("Motorola MC68HC08 Microcontroller")
+    return "Motorola MC68HC08 Microcontroller"
   } else if (p.File.Machine == elf.EM_68HC05) {
-    fmt.Println("Motorola MC68HC05 Microcontroller")
+    return "Motorola MC68HC05 Microcontroller"
   } else if (p.File.Machine == elf.EM_SVX) {
-    fmt.Println("Silicon Graphics SVx")
+    return "Silicon Graphics SVx"
   } else if (p.File.Machine == elf.EM_ST19) {
-    fmt.Println("STMicroelectronics ST19 8-bit microcontroller")
+    return "STMicroelectronics ST19 8-bit microcontroller"
   } else if (p.File.Machine == elf.EM_VAX) {
-    fmt.Println("Digital VAX")
+    return "Digital VAX"
   } else if (p.File.Machine == elf.EM_CRIS) {
-    fmt.Println("Axis Communications 32-bit embedded processor")
+    return "Axis Communications 32-bit embedded processor"
   } else if (p.File.Machine == elf.EM_JAVELIN) {
-    fmt.Println("Infineon Technologies 32-bit embedded processor")
+    return "Infineon Technologies 32-bit embedded processor"
   } else if (p.File.Machine == elf.EM_FIREPATH) {
-    fmt.Println("Element 14 64-bit DSP Processor")
+    return "Element 14 64-bit DSP Processor"
   } else if (p.File.Machine == elf.EM_ZSP) {
-    fmt.Println("LSI Logic 16-bit DSP Processor")
+    return "LSI Logic 16-bit DSP Processor"
   } else if (p.File.Machine == elf.EM_MMIX) {
-    fmt.Println("Donald Knuth's educational 64-bit processor")
+    return "Donald Knuth's educational 64-bit processor"
   } else if (p.File.Machine == elf.EM_HUANY) {
-    fmt.Println("Harvard University machine-independent object files")
+    return "Harvard University machine-independent object files"
   } else if (p.File.Machine == elf.EM_PRISM) {
-    fmt.Println("SiTera Prism")
+    return "SiTera Prism"
   } else if (p.File.Machine == elf.EM_AVR) {
-    fmt.Println("Atmel AVR 8-bit microcontroller")
+    return "Atmel AVR 8-bit microcontroller"
   } else if (p.File.Machine == elf.EM_FR30) {
-    fmt.Println("Fujitsu FR30")
+    return "Fujitsu FR30"
   } else if (p.File.Machine == elf.EM_D10V) {
-    fmt.Println("Mitsubishi D10V")
+    return "Mitsubishi D10V"
   } else if (p.File.Machine == elf.EM_D30V) {
-    fmt.Println("Mitsubishi D30V")
+    return "Mitsubishi D30V"
   } else if (p.File.Machine == elf.EM_V850) {
-    fmt.Println("NEC v850")
+    return "NEC v850"
   } else if (p.File.Machine == elf.EM_M32R) {
-    fmt.Println("Mitsubishi M32R")
+    return "Mitsubishi M32R"
   } else if (p.File.Machine == elf.EM_MN10300) {
-    fmt.Println("Matsushita MN10300")
+    return "Matsushita MN10300"
   } else if (p.File.Machine == elf.EM_MN10200) {
-    fmt.Println("Matsushita MN10200")
+    return "Matsushita MN10200"
   } else if (p.File.Machine == elf.EM_PJ) {
-    fmt.Println("picoJava")
+    return "picoJava"
   } else if (p.File.Machine == elf.EM_OPENRISC) {
-    fmt.Println("OpenRISC 32-bit embedded processor")
+    return "OpenRISC 32-bit embedded processor"
   } else if (p.File.Machine == elf.EM_ARC_COMPACT) {
-    fmt.Println("ARC International ARCompact processor (old spelling/synonym: EM_ARC_A5)")
+    return "ARC International ARCompact processor (old spelling/synonym: EM_ARC_A5)"
   } else if (p.File.Machine == elf.EM_XTENSA) {
-    fmt.Println("Tensilica Xtensa Architecture")
+    return "Tensilica Xtensa Architecture"
   } else if (p.File.Machine == elf.EM_VIDEOCORE) {
-    fmt.Println("Alphamosaic VideoCore processor")
+    return "Alphamosaic VideoCore processor"
   } else if (p.File.Machine == elf.EM_TMM_GPP) {
-    fmt.Println("Thompson Multimedia General Purpose Processor")
+    return "Thompson Multimedia General Purpose Processor"
   } else if (p.File.Machine == elf.EM_NS32K) {
-    fmt.Println("National Semiconductor 32000 series")
+    return "National Semiconductor 32000 series"
   } else if (p.File.Machine == elf.EM_TPC) {
-    fmt.Println("Tenor Network TPC processor")
+    return "Tenor Network TPC processor"
   } else if (p.File.Machine == elf.EM_SNP1K) {
-    fmt.Println("Trebia SNP 1000 processor")
+    return "Trebia SNP 1000 processor"
   } else if (p.File.Machine == elf.EM_ST200) {
-    fmt.Println("ST200 microcontroller")
+    return "ST200 microcontroller"
   } else if (p.File.Machine == elf.EM_IP2K) {
-    fmt.Println("Ubicom IP2xxx microcontroller family")
+    return "Ubicom IP2xxx microcontroller family"
   } else if (p.File.Machine == elf.EM_MAX) {
-    fmt.Println("MAX Processor")
+    return "MAX Processor"
   } else if (p.File.Machine == elf.EM_CR) {
-    fmt.Println("National Semiconductor CompactRISC microprocessor")
+    return "National Semiconductor CompactRISC microprocessor"
   } else if (p.File.Machine == elf.EM_F2MC16) {
-    fmt.Println("Fujitsu F2MC16")
+    return "Fujitsu F2MC16"
   } else if (p.File.Machine == elf.EM_MSP430) {
-    fmt.Println("Texas Instruments embedded microcontroller msp430")
+    return "Texas Instruments embedded microcontroller msp430"
   } else if (p.File.Machine == elf.EM_BLACKFIN) {
-    fmt.Println("Analog Devices Blackfin (DSP) processor")
+    return "Analog Devices Blackfin (DSP) processor"
   } else if (p.File.Machine == elf.EM_SE_C33) {
-    fmt.Println("S1C33 Family of Seiko Epson processors")
+    return "S1C33 Family of Seiko Epson processors"
   } else if (p.File.Machine == elf.EM_SEP) {
-    fmt.Println("Sharp embedded microprocessor")
+    return "Sharp embedded microprocessor"
   } else if (p.File.Machine == elf.EM_ARCA) {
-    fmt.Println("Arca RISC Microprocessor")
+    return "Arca RISC Microprocessor"
   } else if (p.File.Machine == elf.EM_UNICORE) {
-    fmt.Println("Microprocessor series from PKU-Unity Ltd. and MPRC of Peking University")
+    return "Microprocessor series from PKU-Unity Ltd. and MPRC of Peking University"
   } else if (p.File.Machine == elf.EM_EXCESS) {
-    fmt.Println("eXcess: 16/32/64-bit configurable embedded CPU")
+    return "eXcess: 16/32/64-bit configurable embedded CPU"
   } else if (p.File.Machine == elf.EM_DXP) {
-    fmt.Println("Icera Semiconductor Inc. Deep Execution Processor")
+    return "Icera Semiconductor Inc. Deep Execution Processor"
   } else if (p.File.Machine == elf.EM_ALTERA_NIOS2) {
-    fmt.Println("Altera Nios II soft-core processor")
+    return "Altera Nios II soft-core processor"
   } else if (p.File.Machine == elf.EM_CRX) {
-    fmt.Println("National Semiconductor CompactRISC CRX microprocessor")
+    return "National Semiconductor CompactRISC CRX microprocessor"
   } else if (p.File.Machine == elf.EM_XGATE) {
-    fmt.Println("Motorola XGATE embedded processor")
+    return "Motorola XGATE embedded processor"
   } else if (p.File.Machine == elf.EM_C166) {
-    fmt.Println("Infineon C16x/XC16x processor")
+    return "Infineon C16x/XC16x processor"
   } else if (p.File.Machine == elf.EM_M16C) {
-    fmt.Println("Renesas M16C series microprocessors")
+    return "Renesas M16C series microprocessors"
   } else if (p.File.Machine == elf.EM_DSPIC30F) {
-    fmt.Println("Microchip Technology dsPIC30F Digital Signal Controller")
+    return "Microchip Technology dsPIC30F Digital Signal Controller"
   } else if (p.File.Machine == elf.EM_CE) {
-    fmt.Println("Freescale Communication Engine RISC core")
+    return "Freescale Communication Engine RISC core"
   } else if (p.File.Machine == elf.EM_M32C) {
-    fmt.Println("Renesas M32C series microprocessors")
+    return "Renesas M32C series microprocessors"
   } else if (p.File.Machine == elf.EM_TSK3000) {
-    fmt.Println("Altium TSK3000 core")
+    return "Altium TSK3000 core"
   } else if (p.File.Machine == elf.EM_RS08) {
-    fmt.Println("Freescale RS08 embedded processor")
+    return "Freescale RS08 embedded processor"
   } else if (p.File.Machine == elf.EM_SHARC) {
-    fmt.Println("Analog Devices SHARC family of 32-bit DSP processors")
+    return "Analog Devices SHARC family of 32-bit DSP processors"
   } else if (p.File.Machine == elf.EM_ECOG2) {
-    fmt.Println("Cyan Technology eCOG2 microprocessor")
+    return "Cyan Technology eCOG2 microprocessor"
   } else if (p.File.Machine == elf.EM_SCORE7) {
-    fmt.Println("Sunplus S+core7 RISC processor")
+    return "Sunplus S+core7 RISC processor"
   } else if (p.File.Machine == elf.EM_DSP24) {
-    fmt.Println("24-bit DSP Processor")
+    return "24-bit DSP Processor"
   } else if (p.File.Machine == elf.EM_VIDEOCORE3) {
-    fmt.Println("Broadcom VideoCore III processor")
+    return "Broadcom VideoCore III processor"
   } else if (p.File.Machine == elf.EM_LATTICEMICO32) {
-    fmt.Println("RISC processor for Lattice FPGA architecture")
+    return "RISC processor for Lattice FPGA architecture"
   } else if (p.File.Machine == elf.EM_SE_C17) {
-    fmt.Println("Seiko Epson C17 family")
+    return "Seiko Epson C17 family"
   } else if (p.File.Machine == elf.EM_TI_C6000) {
-    fmt.Println("The Texas Instruments TMS320C6000 DSP family")
+    return "The Texas Instruments TMS320C6000 DSP family"
   } else if (p.File.Machine == elf.EM_TI_C2000) {
-    fmt.Println("The Texas Instruments TMS320C2000 DSP family")
+    return "The Texas Instruments TMS320C2000 DSP family"
   } else if (p.File.Machine == elf.EM_TI_C5500) {
-    fmt.Println("The Texas Instruments TMS320C55x DSP family")
+    return "The Texas Instruments TMS320C55x DSP family"
   } else if (p.File.Machine == elf.EM_TI_ARP32) {
-    fmt.Println("Texas Instruments Application Specific RISC Processor, 32bit fetch")
+    return "Texas Instruments Application Specific RISC Processor, 32bit fetch"
   } else if (p.File.Machine == elf.EM_TI_PRU) {
-    fmt.Println("Texas Instruments Programmable Realtime Unit")
+    return "Texas Instruments Programmable Realtime Unit"
   } else if (p.File.Machine == elf.EM_MMDSP_PLUS) {
-    fmt.Println("STMicroelectronics 64bit VLIW Data Signal Processor")
+    return "STMicroelectronics 64bit VLIW Data Signal Processor"
   } else if (p.File.Machine == elf.EM_CYPRESS_M8C) {
-    fmt.Println("Cypress M8C microprocessor")
+    return "Cypress M8C microprocessor"
   } else if (p.File.Machine == elf.EM_R32C) {
-    fmt.Println("Renesas R32C series microprocessors")
+    return "Renesas R32C series microprocessors"
   } else if (p.File.Machine == elf.EM_TRIMEDIA) {
-    fmt.Println("NXP Semiconductors TriMedia architecture family")
+    return "NXP Semiconductors TriMedia architecture family"
   } else if (p.File.Machine == elf.EM_QDSP6) {
-    fmt.Println("QUALCOMM DSP6 Processor")
+    return "QUALCOMM DSP6 Processor"
   } else if (p.File.Machine == elf.EM_8051) {
-    fmt.Println("Intel 8051 and variants")
+    return "Intel 8051 and variants"
   } else if (p.File.Machine == elf.EM_STXP7X) {
-    fmt.Println("STMicroelectronics STxP7x family of configurable and extensible RISC processors")
+    return "STMicroelectronics STxP7x family of configurable and extensible RISC processors"
   } else if (p.File.Machine == elf.EM_NDS32) {
-    fmt.Println("Andes Technology compact code size embedded RISC processor family")
+    return "Andes Technology compact code size embedded RISC processor family"
   } else if (p.File.Machine == elf.EM_ECOG1) {
-    fmt.Println("Cyan Technology eCOG1X family")
+    return "Cyan Technology eCOG1X family"
   } else if (p.File.Machine == elf.EM_ECOG1X) {
-    fmt.Println("Cyan Technology eCOG1X family")
+    return "Cyan Technology eCOG1X family"
   } else if (p.File.Machine == elf.EM_MAXQ30) {
-    fmt.Println("Dallas Semiconductor MAXQ30 Core Micro-controllers")
+    return "Dallas Semiconductor MAXQ30 Core Micro-controllers"
   } else if (p.File.Machine == elf.EM_XIMO16) {
-    fmt.Println("16-bit DSP Processor")
+    return "16-bit DSP Processor"
   } else if (p.File.Machine == elf.EM_MANIK) {
-    fmt.Println("M2000 Reconfigurable RISC Microprocessor")
+    return "M2000 Reconfigurable RISC Microprocessor"
   } else if (p.File.Machine == elf.EM_CRAYNV2) {
-    fmt.Println("Cray Inc. NV2 vector architecture")
+    return "Cray Inc. NV2 vector architecture"
   } else if (p.File.Machine == elf.EM_RX) {
-    fmt.Println("Renesas RX family")
+    return "Renesas RX family"
   } else if (p.File.Machine == elf.EM_METAG) {
-    fmt.Println("Imagination Technologies META processor architecture")
+    return "Imagination Technologies META processor architecture"
   } else if (p.File.Machine == elf.EM_MCST_ELBRUS) {
-    fmt.Println("MCST Elbrus general purpose hardware architecture")
+    return "MCST Elbrus general purpose hardware architecture"
   } else if (p.File.Machine == elf.EM_ECOG16) {
-    fmt.Println("Cyan Technology eCOG16 family")
+    return "Cyan Technology eCOG16 family"
   } else if (p.File.Machine == elf.EM_CR16) {
-    fmt.Println("National Semiconductor CompactRISC CR16 16-bit microprocessor")
+    return "National Semiconductor CompactRISC CR16 16-bit microprocessor"
   } else if (p.File.Machine == elf.EM_ETPU) {
-    fmt.Println("Freescale Extended Time Processing Unit")
+    return "Freescale Extended Time Processing Unit"
   } else if (p.File.Machine == elf.EM_SLE9X) {
-    fmt.Println("Infineon Technologies SLE9X core")
+    return "Infineon Technologies SLE9X core"
   } else if (p.File.Machine == elf.EM_L10M) {
-    fmt.Println("Intel L10M")
+    return "Intel L10M"
   } else if (p.File.Machine == elf.EM_K10M) {
-    fmt.Println("Intel K10M")
+    return "Intel K10M"
   } else if (p.File.Machine == elf.EM_AARCH64) {
-    fmt.Println("ARM 64-bit Architecture (AArch64)")
+    return "ARM 64-bit Architecture (AArch64)"
   } else if (p.File.Machine == elf.EM_AVR32) {
-    fmt.Println("Atmel Corporation 32-bit microprocessor family")
+    return "Atmel Corporation 32-bit microprocessor family"
   } else if (p.File.Machine == elf.EM_STM8) {
-    fmt.Println("STMicroeletronics STM8 8-bit microcontroller")
+    return "STMicroeletronics STM8 8-bit microcontroller"
   } else if (p.File.Machine == elf.EM_TILE64) {
-    fmt.Println("Tilera TILE64 multicore architecture family")
+    return "Tilera TILE64 multicore architecture family"
   } else if (p.File.Machine == elf.EM_TILEPRO) {
-    fmt.Println("Tilera TILEPro multicore architecture family")
+    return "Tilera TILEPro multicore architecture family"
   } else if (p.File.Machine == elf.EM_MICROBLAZE) {
-    fmt.Println("Xilinx MicroBlaze 32-bit RISC soft processor core")
+    return "Xilinx MicroBlaze 32-bit RISC soft processor core"
   } else if (p.File.Machine == elf.EM_CUDA) {
-    fmt.Println("NVIDIA CUDA architecture")
+    return "NVIDIA CUDA architecture"
   } else if (p.File.Machine == elf.EM_TILEGX) {
-    fmt.Println("Tilera TILE-Gx multicore architecture family")
+    return "Tilera TILE-Gx multicore architecture family"
   } else if (p.File.Machine == elf.EM_CLOUDSHIELD) {
-    fmt.Println("CloudShield architecture family")
+    return "CloudShield architecture family"
   } else if (p.File.Machine == elf.EM_COREA_1ST) {
-    fmt.Println("KIPO-KAIST Core-A 1st generation processor family")
+    return "KIPO-KAIST Core-A 1st generation processor family"
   } else if (p.File.Machine == elf.EM_COREA_2ND) {
-    fmt.Println("KIPO-KAIST Core-A 2nd generation processor family")
+    return "KIPO-KAIST Core-A 2nd generation processor family"
   } else if (p.File.Machine == elf.EM_ARC_COMPACT2) {
-    fmt.Println("Synopsys ARCompact V2")
+    return "Synopsys ARCompact V2"
   } else if (p.File.Machine == elf.EM_OPEN8) {
-    fmt.Println("Open8 8-bit RISC soft processor core")
+    return "Open8 8-bit RISC soft processor core"
   } else if (p.File.Machine == elf.EM_RL78) {
-    fmt.Println("Renesas RL78 family")
+    return "Renesas RL78 family"
   } else if (p.File.Machine == elf.EM_VIDEOCORE5) {
-    fmt.Println("Broadcom VideoCore V processor")
+    return "Broadcom VideoCore V processor"
   } else if (p.File.Machine == elf.EM_78KOR) {
-    fmt.Println("Renesas 78KOR family")
+    return "Renesas 78KOR family"
   } else if (p.File.Machine == elf.EM_56800EX) {
-    fmt.Println("Freescale 56800EX Digital Signal Controller (DSC)")
+    return "Freescale 56800EX Digital Signal Controller (DSC)"
   } else if (p.File.Machine == elf.EM_BA1) {
-    fmt.Println("Beyond BA1 CPU architecture")
+    return "Beyond BA1 CPU architecture"
   } else if (p.File.Machine == elf.EM_BA2) {
-    fmt.Println("Beyond BA2 CPU architecture")
+    return "Beyond BA2 CPU architecture"
   } else if (p.File.Machine == elf.EM_XCORE) {
-    fmt.Println("XMOS xCORE processor family")
+    return "XMOS xCORE processor family"
   } else if (p.File.Machine == elf.EM_MCHP_PIC) {
-    fmt.Println("Microchip 8-bit PIC(r) family")
+    return "Microchip 8-bit PIC(r) family"
   } else if (p.File.Machine == elf.EM_INTEL205) {
-    fmt.Println("Reserved by Intel")
+    return "Reserved by Intel"
   } else if (p.File.Machine == elf.EM_INTEL206) {
-    fmt.Println("Reserved by Intel")
+    return "Reserved by Intel"
   } else if (p.File.Machine == elf.EM_INTEL207) {
-    fmt.Println("Reserved by Intel")
+    return "Reserved by Intel"
   } else if (p.File.Machine == elf.EM_INTEL208) {
-    fmt.Println("Reserved by Intel")
+    return "Reserved by Intel"
   } else if (p.File.Machine == elf.EM_INTEL209) {
-    fmt.Println("Reserved by Intel")
+    return "Reserved by Intel"
   } else if (p.File.Machine == elf.EM_KM32) {
-    fmt.Println("KM211 KM32 32-bit processor")
+    return "KM211 KM32 32-bit processor"
   } else if (p.File.Machine == elf.EM_KMX32) {
-    fmt.Println("KM211 KMX32 32-bit processor")
+    return "KM211 KMX32 32-bit processor"
   } else if (p.File.Machine == elf.EM_KMX16) {
-    fmt.Println("KM211 KMX16 16-bit processor")
+    return "KM211 KMX16 16-bit processor"
   } else if (p.File.Machine == elf.EM_KMX8) {
-    fmt.Println("KM211 KMX8 8-bit processor")
+    return "KM211 KMX8 8-bit processor"
   } else if (p.File.Machine == elf.EM_KVARC) {
-    fmt.Println("KM211 KVARC processor")
+    return "KM211 KVARC processor"
   } else if (p.File.Machine == elf.EM_CDP) {
-    fmt.Println("Paneve CDP architecture family")
+    return "Paneve CDP architecture family"
   } else if (p.File.Machine == elf.EM_COGE) {
-    fmt.Println("Cognitive Smart Memory Processor")
+    return "Cognitive Smart Memory Processor"
   } else if (p.File.Machine == elf.EM_COOL) {
-    fmt.Println("Bluechip Systems CoolEngine")
+    return "Bluechip Systems CoolEngine"
   } else if (p.File.Machine == elf.EM_NORC) {
-    fmt.Println("Nanoradio Optimized RISC")
+    return "Nanoradio Optimized RISC"
   } else if (p.File.Machine == elf.EM_CSR_KALIMBA) {
-    fmt.Println("CSR Kalimba architecture family")
+    return "CSR Kalimba architecture family"
   } else if (p.File.Machine == elf.EM_Z80) {
-    fmt.Println("Zilog Z80")
+    return "Zilog Z80"
   } else if (p.File.Machine == elf.EM_VISIUM) {
-    fmt.Println("Controls and Data Services VISIUMcore processor")
+    return "Controls and Data Services VISIUMcore processor"
   } else if (p.File.Machine == elf.EM_FT32) {
-    fmt.Println("FTDI Chip FT32 high performance 32-bit RISC architecture")
+    return "FTDI Chip FT32 high performance 32-bit RISC architecture"
   } else if (p.File.Machine == elf.EM_MOXIE) {
-    fmt.Println("Moxie processor family")
+    return "Moxie processor family"
   } else if (p.File.Machine == elf.EM_AMDGPU) {
-    fmt.Println("AMD GPU architecture")
+    return "AMD GPU architecture"
   } else if (p.File.Machine == elf.EM_RISCV) {
-    fmt.Println("RISC-V")
+    return "RISC-V"
   } else if (p.File.Machine == elf.EM_LANAI) {
-    fmt.Println("Lanai 32-bit processor")
+    return "Lanai 32-bit processor"
   } else if (p.File.Machine == elf.EM_BPF) {
-    fmt.Println("Linux BPF – in-kernel virtual machine")
+    return "Linux BPF – in-kernel virtual machine"
   } else {
-    fmt.Println("Unknown")
+    return "Unknown"
   }
 }
 
-func (p *Information) ShowSymbols() {
+func (p *Information) GetInformation() Header {
+  return Header{
+    Class: p.File.Class.String(), ClassDescription: p.GetClassDescription(),
+    Data: p.File.Data.String(), DataDescription: p.GetDataDescription(),
+    OSABI: p.File.OSABI.String(), OSABIDescription: p.GetOSABIDescription(),
+    Type: p.File.Type.String(), TypeDescription: p.GetTypeDescription(),
+    Machine: p.File.Machine.String(), MachineDescription: p.GetMachineDescription(),
+    Entry: p.File.Entry}
+}
+
+func (p *Information) ShowInformation() {
+  header := p.GetInformation()
+
+  if p.Output == "json" {
+    misc.ShowJson(header)
+
+    return
+  }
+
+  fmt.Println("Class:[" + header.Class + "]: " + header.ClassDescription)
+  fmt.Println("Endianess:[" + header.Data + "]: " + header.DataDescription)
+  fmt.Println("System ABI:[" + header.OSABI + "]: " + header.OSABIDescription)
+  fmt.Println("Type:[" + header.Type + "]: " + header.TypeDescription)
+  fmt.Println("Machine:[" + header.Machine + "]: " + header.MachineDescription)
+}
+
+type Symbol struct {
+  Name string `json:"name"`
+  Value uint64 `json:"value"`
+  Size uint64 `json:"size"`
+  Type string `json:"type"`
+  Bind string `json:"bind"`
+  Dynamic bool `json:"dynamic"`
+}
+
+type Section struct {
+  Name string `json:"name"`
+  Type string `json:"type"`
+  Addr uint64 `json:"addr"`
+  Offset uint64 `json:"offset"`
+  Size uint64 `json:"size"`
+}
+
+type Segment struct {
+  Type string `json:"type"`
+  Flags string `json:"flags"`
+  Vaddr uint64 `json:"vaddr"`
+  Paddr uint64 `json:"paddr"`
+  Offset uint64 `json:"offset"`
+  Filesz uint64 `json:"filesz"`
+  Memsz uint64 `json:"memsz"`
+  Align uint64 `json:"align"`
+  Interpreter string `json:"interpreter,omitempty"`
+  Sections []string `json:"sections"`
+}
+
+// defined symbols are rebased to the load address of the process in the live view
+func (p *Information) GetSymbolValue(symbol elf.Symbol) uint64 {
+  if symbol.Section == elf.SHN_UNDEF || symbol.Section == elf.SHN_ABS || elf.ST_TYPE(symbol.Info) == elf.STT_TLS {
+    return symbol.Value
+  }
+
+  return p.ViewAddress(symbol.Value)
+}
+
+func (p *Information) GetSymbols() []Symbol {
+  symbols := []Symbol{}
+
   for _, symbol := range p.Symbols {
     if len(symbol.Name) > 0 {
-      fmt.Printf(
-        "%32s [0x%08x, %d]\n",
-        symbol.Name, symbol.Value, symbol.Size)
+      symbols = append(symbols, Symbol{
+        Name: symbol.Name, Value: p.GetSymbolValue(symbol), Size: symbol.Size,
+        Type: elf.ST_TYPE(symbol.Info).String(), Bind: elf.ST_BIND(symbol.Info).String()})
     }
   }
 
   for _, symbol := range p.DynamicSymbols {
     if len(symbol.Name) > 0 {
-      fmt.Printf(
-        "%32s [0x%08x, %d]\n",
-        symbol.Name, symbol.Value, symbol.Size)
+      symbols = append(symbols, Symbol{
+        Name: symbol.Name, Value: p.GetSymbolValue(symbol), Size: symbol.Size,
+        Type: elf.ST_TYPE(symbol.Info).String(), Bind: elf.ST_BIND(symbol.Info).String(), Dynamic: true})
     }
   }
 
-  if len(p.Symbols) == 0 && len(p.DynamicSymbols) == 0 {
+  return symbols
+}
+
+func (p *Information) ShowSymbols() {
+  symbols := p.GetSymbols()
+
+  if p.Output == "json" {
+    misc.ShowJson(symbols)
+
+    return
+  }
+
+  for _, symbol := range symbols {
+    fmt.Printf(
+      "%32s [0x%08x, %d]\n",
+      symbol.Name, symbol.Value, symbol.Size)
+  }
+
+  if len(symbols) == 0 {
     fmt.Println("no symbols found")
   }
 }
 
-func (p *Information) ShowSections() {
-  fmt.Printf("Entry Addr: 0x%08x\n", p.File.Entry)
+func (p *Information) GetSections() []Section {
+  sections := []Section{}
 
   for _, section := range p.Sections {
     if len(section.Name) > 0 {
-      fmt.Printf(
-        "%32s [addr:0x%08x off:0x%08x size:0x%08x]\n",
-        section.Name, section.Addr, section.Offset, section.Size)
+      sections = append(sections, Section{
+        Name: section.Name, Type: section.Type.String(), Addr: section.Addr, Offset: section.Offset, Size: section.Size})
     }
   }
 
-  if len(p.Sections) == 0 {
+  return sections
+}
+
+func (p *Information) ShowSections() {
+  sections := p.GetSections()
+
+  if p.Output == "json" {
+    misc.ShowJson(sections)
+
+    return
+  }
+
+  fmt.Printf("Entry Addr: 0x%08x\n", p.File.Entry)
+
+  for _, section := range sections {
+    fmt.Printf(
+      "%32s [addr:0x%08x off:0x%08x size:0x%08x]\n",
+      section.Name, section.Addr, section.Offset, section.Size)
+  }
+
+  if len(sections) == 0 {
     fmt.Println("no sections found")
   }
 }
 
+func (p *Information) GetSegmentFlags(flags elf.ProgFlag) string {
+  rwx := []byte("   ")
+
+  if flags & elf.PF_R != 0 {
+    rwx[0] = 'R'
+  }
+
+  if flags & elf.PF_W != 0 {
+    rwx[1] = 'W'
+  }
+
+  if flags & elf.PF_X != 0 {
+    rwx[2] = 'E'
+  }
+
+  return string(rwx)
+}
+
+func (p *Information) IsSectionInSegment(section *elf.Section, prog *elf.Prog) bool {
+  if section.Type == elf.SHT_NULL {
+    return false
+  }
+
+  // tls zero-fill only occupies memory in the PT_TLS segment
+  if section.Type == elf.SHT_NOBITS && section.Flags & elf.SHF_TLS != 0 && prog.Type != elf.PT_TLS {
+    return false
+  }
+
+  if section.Type != elf.SHT_NOBITS {
+    if section.Offset < prog.Off || section.Offset + section.Size > prog.Off + prog.Filesz {
+      return false
+    }
+
+    if section.Size == 0 && section.Offset >= prog.Off + prog.Filesz {
+      return false
+    }
+  }
+
+  if section.Flags & elf.SHF_ALLOC == 0 {
+    return prog.Type != elf.PT_LOAD && prog.Type != elf.PT_DYNAMIC && prog.Type != elf.PT_GNU_RELRO && prog.Vaddr == 0
+  }
+
+  if section.Addr < prog.Vaddr || section.Addr + section.Size > prog.Vaddr + prog.Memsz {
+    return false
+  }
+
+  return section.Size != 0 || section.Addr < prog.Vaddr + prog.Memsz
+}
+
+func (p *Information) GetSegments() []Segment {
+  segments := []Segment{}
+
+  for _, prog := range p.File.Progs {
+    segment := Segment{
+      Type: strings.TrimPrefix(prog.Type.String(), "PT_"), Flags: p.GetSegmentFlags(prog.Flags),
+      Vaddr: prog.Vaddr, Paddr: prog.Paddr, Offset: prog.Off, Filesz: prog.Filesz, Memsz: prog.Memsz, Align: prog.Align}
+
+    if prog.Type == elf.PT_INTERP {
+      if data, e := p.GetSegmentData(prog); e == nil {
+        segment.Interpreter = strings.TrimRight(string(data), "\x00")
+      }
+    }
+
+    segment.Sections = []string{}
+
+    for _, section := range p.File.Sections {
+      if p.IsSectionInSegment(section, prog) {
+        segment.Sections = append(segment.Sections, section.Name)
+      }
+    }
+
+    segments = append(segments, segment)
+  }
+
+  return segments
+}
+
+func (p *Information) ShowSegments() {
+  segments := p.GetSegments()
+
+  if p.Output == "json" {
+    misc.ShowJson(segments)
+
+    return
+  }
+
+  fmt.Printf("Entry Addr: 0x%08x\n", p.File.Entry)
+
+  if len(segments) == 0 {
+    fmt.Println("no segments found")
+
+    return
+  }
+
+  fmt.Printf(
+    "%4s %-14s %3s %-18s %-18s %-10s %-10s %-10s %s\n",
+    "", "Type", "Flg", "VirtAddr", "PhysAddr", "Offset", "FileSiz", "MemSiz", "Align")
+
+  for i, segment := range segments {
+    fmt.Printf(
+      "%4d %-14s %3s 0x%016x 0x%016x 0x%08x 0x%08x 0x%08x 0x%x\n",
+      i, segment.Type, segment.Flags, segment.Vaddr, segment.Paddr, segment.Offset, segment.Filesz, segment.Memsz, segment.Align)
+
+    if len(segment.Interpreter) > 0 {
+      fmt.Printf("%4s [interpreter: %s]\n", "", segment.Interpreter)
+    }
+  }
+
+  fmt.Println("Section to segment mapping:")
+
+  for i, segment := range segments {
+    fmt.Printf("%4d %s\n", i, strings.Join(segment.Sections, " "))
+  }
+}
+
+type Dynamic struct {
+  Tag uint64 `json:"tag"`
+  Name string `json:"name"`
+  Value uint64 `json:"value"`
+  Text string `json:"text"`
+}
+
+func (p *Information) GetDynamic() []Dynamic {
+  dynamic := []Dynamic{}
+
+  for _, entry := range p.Dynamic {
+    dynamic = append(dynamic, Dynamic{
+      Tag: uint64(entry.Tag), Name: strings.TrimPrefix(entry.Tag.String(), "DT_"), Value: entry.Value, Text: entry.Text})
+  }
+
+  return dynamic
+}
+
+func (p *Information) ShowDynamic() {
+  if p.Analyzed == false {
+    fmt.Println("Call 'analyze' before")
+
+    return
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(p.GetDynamic())
+
+    return
+  }
+
+  if len(p.Dynamic) == 0 {
+    fmt.Println("no dynamic section found")
+
+    return
+  }
+
+  for _, entry := range p.Dynamic {
+    fmt.Printf(
+      "0x%016x %-20s %s\n",
+      uint64(entry.Tag), strings.TrimPrefix(entry.Tag.String(), "DT_"), entry.Text)
+  }
+}
+
+func (p *Information) GetRelocationText(relocation state.Relocation) string {
+  text := relocation.Symbol
+
+  if relocation.HasAddend {
+    if relocation.Addend < 0 {
+      text = fmt.Sprintf("%s - 0x%x", text, -relocation.Addend)
+    } else {
+      text = fmt.Sprintf("%s + 0x%x", text, relocation.Addend)
+    }
+  }
+
+  return strings.TrimPrefix(text, " ")
+}
+
+func (p *Information) ShowRelocations() {
+  if p.Analyzed == false {
+    fmt.Println("Call 'analyze' before")
+
+    return
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(append([]state.Relocation{}, p.Relocations...))
+
+    return
+  }
+
+  if len(p.Relocations) == 0 {
+    fmt.Println("no relocations found")
+
+    return
+  }
+
+  section := ""
+
+  for _, relocation := range p.Relocations {
+    if relocation.Section != section {
+      section = relocation.Section
+
+      fmt.Printf("%s:\n", section)
+    }
+
+    fmt.Printf(
+      "  0x%016x %-28s 0x%016x %s\n",
+      relocation.Address, relocation.TypeName, relocation.SymbolValue, p.GetRelocationText(relocation))
+  }
+}
+
 func (p *Information) GetStringFromAddress(addr uint64) (string, error) {
-  if addr >= uint64(len(p.Data)) {
-    return "", err.NoStringFound // CHANGE:: out of range
+  data, e := p.ReadVirtual(addr, 256)
+
+  if e != nil || len(data) == 0 {
+    return "", err.NoStringFound
   }
 
   r, _ := regexp.Compile(`[\d\w\s,.!?@#$%^&*()-_=+{}\[\];:'"<>~?/\\]+`)
-  s := r.FindAllString(string(p.Data[addr:]), -1)
+  s := r.FindAllString(string(data), -1)
 
   if len(s) > 0 {
     return "\"" + s[0] + "\"", nil
@@ -529,24 +855,80 @@ func (p *Information) GetStringFromAddress(addr uint64) (string, error) {
 
 func (p *Information) GetSymbolFromAddress(addr uint64) (string, error) {
   for _, symbol := range p.Symbols {
-    if len(symbol.Name) > 0 {
-      if symbol.Value == addr {
+    if len(symbol.Name) > 0 && strings.HasPrefix(symbol.Name, "$") == false {
+      value := symbol.Value
+
+      if p.File.Machine == elf.EM_ARM && elf.ST_TYPE(symbol.Info) == elf.STT_FUNC {
+        value = value &^ 1 // thumb bit
+      }
+
+      if value == addr {
         return symbol.Name, nil
       }
     }
   }
 
+  if name, found := p.PltSymbols[addr]; found {
+    return name, nil
+  }
+
   return "", err.NoSymbolFound
 }
 
+// finds the function or object containing the address, returning the offset inside of it
+func (p *Information) GetNearestSymbol(addr uint64) (string, uint64, error) {
+  var best *elf.Symbol
+  var bestValue uint64
+
+  section, e := p.GetSectionFromAddress(addr)
+
+  for i, symbol := range p.Symbols {
+    t := elf.ST_TYPE(symbol.Info)
+
+    if e != nil || len(symbol.Name) == 0 || strings.HasPrefix(symbol.Name, "$") || (t != elf.STT_FUNC && t != elf.STT_OBJECT) {
+      continue
+    }
+
+    // symbols without size only cover the addresses of their own section
+    if symbol.Size == 0 && (int(symbol.Section) >= len(p.File.Sections) || p.File.Sections[symbol.Section] != section) {
+      continue
+    }
+
+    value := symbol.Value
+
+    if p.File.Machine == elf.EM_ARM && t == elf.STT_FUNC {
+      value = value &^ 1 // thumb bit
+    }
+
+    if value > addr || (symbol.Size > 0 && addr >= value + symbol.Size) {
+      continue
+    }
+
+    if best == nil || value > bestValue {
+      best = &p.Symbols[i]
+      bestValue = value
+    }
+  }
+
+  if best != nil {
+    return best.Name, addr - bestValue, nil
+  }
+
+  if name, e := p.GetSymbolFromAddress(addr); e == nil {
+    return name, 0, nil
+  }
+
+  return "", 0, err.NoSymbolFound
+}
+
 func (p *Information) GetAddressFromLea(addr uint64, code string) (uint64, error) {
-  r, _ := regexp.Compile(`lea .*, \[([-+]?.*)\]`)
+  r, _ := regexp.Compile(`lea .*, \[(?:rip)?([-+]?.*)\]`)
   s := r.FindAllSubmatch([]byte(code), -1)
 
   if len(s) > 0 {
     offsetStr := string(s[0][1])
 
-    offset, err := strconv.ParseInt(strings.Replace(offsetStr, "0x", "", -1), 16, 64)
+    offset, err := strconv.ParseInt(offsetStr, 0, 64)
 
     if err == nil {
       return uint64(int64(addr) + offset), nil
@@ -557,13 +939,13 @@ func (p *Information) GetAddressFromLea(addr uint64, code string) (uint64, error
 }
 
 func (p *Information) GetAddressFromCall(addr uint64, code string) (uint64, error) {
-  r, _ := regexp.Compile(`call \[([-+]?.*)\]`)
+  r, _ := regexp.Compile(`call (?:qword ptr )?\[(?:rip)?([-+]?.*)\]`)
   s := r.FindAllSubmatch([]byte(code), -1)
 
   if len(s) > 0 {
     offsetStr := string(s[0][1])
 
-    offset, err := strconv.ParseInt(strings.Replace(offsetStr, "0x", "", -1), 16, 64)
+    offset, err := strconv.ParseInt(offsetStr, 0, 64)
 
     if err == nil {
       return uint64(int64(addr) + offset), nil
@@ -580,7 +962,7 @@ func (p *Information) GetAddressFromCallOffset(addr uint64, code string) (uint64
   if len(s) > 0 {
     offsetStr := string(s[0][1])
 
-    offset, err := strconv.ParseInt(strings.Replace(offsetStr, "0x", "", -1), 16, 64)
+    offset, err := strconv.ParseInt(offsetStr, 0, 64)
 
     if err == nil {
       return uint64(int64(addr) + offset), nil
@@ -597,7 +979,7 @@ func (p *Information) GetAddressFromJump(addr uint64, code string) (uint64, erro
   if len(s) > 0 {
     offsetStr := string(s[0][2])
 
-    offset, err := strconv.ParseInt(strings.Replace(offsetStr, "0x", "", -1), 16, 64)
+    offset, err := strconv.ParseInt(offsetStr, 0, 64)
 
     if err == nil {
       return uint64(int64(addr) + offset), nil
@@ -607,76 +989,254 @@ func (p *Information) GetAddressFromJump(addr uint64, code string) (uint64, erro
   return 0, err.AddressNotFound
 }
 
-func (p *Information) GetAddressContent(addr uint64, code string) string {
-  caddr, err := p.GetAddressFromLea(addr, code)
+func (p *Information) GetArmState(addr uint64) asm.ArmState {
+  var mapping *elf.Symbol
+
+  // mapping symbols ($a, $t and $d) mark the start of each kind of content
+  for i, symbol := range p.Symbols {
+    if strings.HasPrefix(symbol.Name, "$a") || strings.HasPrefix(symbol.Name, "$t") || strings.HasPrefix(symbol.Name, "$d") {
+      if symbol.Value <= addr && (mapping == nil || symbol.Value > mapping.Value) {
+        mapping = &p.Symbols[i]
+      }
+    }
+  }
 
-  if err != nil {
-    caddr, err = p.GetAddressFromCall(addr, code)
+  if mapping != nil {
+    if strings.HasPrefix(mapping.Name, "$t") {
+      return asm.ThumbCode
+    } else if strings.HasPrefix(mapping.Name, "$d") {
+      return asm.ArmData
+    }
 
-    if err != nil {
-      caddr, err = p.GetAddressFromCallOffset(addr, code)
+    return asm.ArmCode
+  }
 
-      if err != nil {
-        caddr, err = p.GetAddressFromJump(addr, code)
+  // without mapping symbols, the lsb of the enclosing function tells the state
+  for _, symbol := range p.Symbols {
+    if elf.ST_TYPE(symbol.Info) == elf.STT_FUNC {
+      start := symbol.Value &^ 1
 
-        if err != nil {
-          return ""
+      if addr >= start && addr < start + symbol.Size {
+        if symbol.Value & 1 == 1 {
+          return asm.ThumbCode
         }
+
+        return asm.ArmCode
       }
     }
   }
 
-  str, err := p.GetSymbolFromAddress(caddr)
+  if p.File.Entry & 1 == 1 {
+    return asm.ThumbCode
+  }
+
+  return asm.ArmCode
+}
 
-  if err == nil {
-    return fmt.Sprintf("[0x%08x] %s", caddr, str)
+func (p *Information) GetDisassembler(mode string) (asm.Disassembler, error) {
+  if mode == "" {
+    if p.File.Machine == elf.EM_X86_64 {
+      mode = "64"
+    } else if p.File.Machine == elf.EM_386 {
+      mode = "32"
+    } else if p.File.Machine == elf.EM_ARM {
+      return &asm.Arm{
+        State: p.GetArmState}, nil
+    } else if p.File.Machine == elf.EM_AARCH64 {
+      mode = "arm64"
+    } else {
+      return nil, err.UnsupportedArchitecture
+    }
   }
 
-  str, err = p.GetStringFromAddress(caddr)
+  if mode == "16" || mode == "32" || mode == "64" {
+    bits, _ := strconv.Atoi(mode)
+
+    return &asm.X86{
+      Mode: bits}, nil
+  } else if mode == "arm" {
+    return &asm.Arm{
+      State: func(uint64) asm.ArmState { return asm.ArmCode }}, nil
+  } else if mode == "thumb" {
+    return &asm.Arm{
+      State: func(uint64) asm.ArmState { return asm.ThumbCode }}, nil
+  } else if mode == "arm64" {
+    return &asm.Arm64{}, nil
+  }
 
-  if err == nil {
-    return fmt.Sprintf("[0x%08x] %s", caddr, str)
+  return nil, err.InvalidMode
+}
+
+type Line struct {
+  Address uint64 `json:"address"`
+  Bytes string `json:"bytes"`
+  Instruction string `json:"instruction"`
+  Target *uint64 `json:"target,omitempty"`
+  Symbol string `json:"symbol,omitempty"`
+  String string `json:"string,omitempty"`
+  Relocations []string `json:"relocations,omitempty"`
+  File string `json:"file,omitempty"`
+  Line int `json:"line,omitempty"`
+  Source string `json:"source,omitempty"`
+}
+
+func (p *Information) GetTarget(ins asm.Instruction) (uint64, bool) {
+  if ins.HasTarget {
+    return ins.Target, true
+  }
+
+  next := ins.Address + uint64(len(ins.Bytes))
+
+  for _, get := range []func(uint64, string) (uint64, error){p.GetAddressFromLea, p.GetAddressFromCall, p.GetAddressFromCallOffset, p.GetAddressFromJump} {
+    if caddr, e := get(next, ins.Text); e == nil {
+      return caddr, true
+    }
   }
 
-  return fmt.Sprintf("[0x%08x]", caddr)
+  return 0, false
 }
 
-func (p *Information) ShowAssemble(addr uint64, lines int) error {
-  if p.Analyzed == false {
-    fmt.Println("Call 'analyze' before")
+// the symbol of a shared library containing the address of the live view
+func (p *Information) GetLibrarySymbol(addr uint64) (string, error) {
+  if p.Live == false || p.Libraries == nil {
+    return "", err.NoSymbolFound
+  }
 
-    return nil
+  name, offset, e := p.Libraries.GetLibrarySymbol(addr)
+
+  if e != nil {
+    return "", e
   }
 
-  if addr > (uint64)(len(p.Data)) {
-    fmt.Printf("Address:[0x%08x] is greater than data code:[0x%08x]\n", addr, len(p.Data))
+  if offset > 0 {
+    return fmt.Sprintf("%s+%d", name, offset), nil
+  }
 
-    return nil
+  return name, nil
+}
+
+func (p *Information) GetAssemble(addr uint64, lines int, mode string) ([]Line, error) {
+  disassembler, e := p.GetDisassembler(mode)
+
+  if e != nil {
+    return nil, e
+  }
+
+  if lines <= 0 {
+    return []Line{}, nil
   }
 
-  data := p.Data[addr:]
+  // the longest x86 instruction has 15 bytes
+  data, e := p.ReadVirtual(addr, uint64(lines) * 16)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := []Line{}
+
+  var previous *state.SourceLine
 
-  for i:=0; i<lines; i++ {
-    ins, err := x86asm.Decode(data, 32)
+  for i:=0; i<lines && len(data) > 0; i++ {
+    ins, _ := disassembler.Decode(data, addr)
 
-    if err != nil {
-      // return err
+    line := Line{
+      Address: addr, Bytes: hex.EncodeToString(ins.Bytes), Instruction: ins.Text}
+
+    // the source line is given by the first instruction of its code
+    if source, e := p.GetSourceLine(p.FileAddress(addr)); e == nil && (previous == nil || source.Line != previous.Line || source.File != previous.File) {
+      line.File, line.Line = source.File, source.Line
+      line.Source, _ = p.GetSourceText(source.File, source.Line)
+
+      previous = source
+    } else if e != nil {
+      previous = nil
     }
 
-    instruction := strings.ToLower(ins.String())
-    content := p.GetAddressContent(uint64(int(addr) + ins.Len), instruction)
+    if target, ok := p.GetTarget(ins); ok {
+      line.Target = &target
+
+      if str, e := p.GetSymbolFromAddress(p.FileAddress(target)); e == nil {
+        line.Symbol = str
+      } else if str, e := p.GetLibrarySymbol(target); e == nil {
+        line.Symbol = str
+      } else if str, e := p.GetStringFromAddress(target); e == nil {
+        line.String = str
+      }
+    }
 
-    if len(content) > 0 {
-      content = "; " + content
+    for _, relocation := range p.GetRelocationsFromRange(p.FileAddress(addr), uint64(len(ins.Bytes))) {
+      line.Relocations = append(line.Relocations, relocation.TypeName + " " + p.GetRelocationText(relocation))
     }
 
-    fmt.Printf("0x%08x:  %32v\t%-32v%s\n", addr, instruction, hex.EncodeToString(data[0:ins.Len]), content)
+    result = append(result, line)
 
-    data = data[ins.Len:]
+    data = data[len(ins.Bytes):]
 
-    addr = addr + (uint64)(ins.Len)
+    addr = addr + (uint64)(len(ins.Bytes))
   }
 
-  return nil
+  return result, nil
+}
+
+func (p *Information) GetLineContent(line Line) string {
+  content := ""
+
+  if line.Target != nil {
+    content = fmt.Sprintf("[0x%08x]", *line.Target)
+
+    if len(line.Symbol) > 0 {
+      content = content + " " + line.Symbol
+    } else if len(line.String) > 0 {
+      content = content + " " + line.String
+    }
+  }
+
+  for _, relocation := range line.Relocations {
+    content = content + " <" + relocation + ">"
+  }
+
+  if len(content) > 0 {
+    content = "; " + strings.TrimPrefix(content, " ")
+  }
+
+  return content
 }
 
+func (p *Information) ShowAssemble(addr uint64, lines int, mode string) error {
+  if p.Analyzed == false {
+    fmt.Println("Call 'analyze' before")
+
+    return nil
+  }
+
+  result, e := p.GetAssemble(addr, lines, mode)
+
+  if e == err.ZeroFill {
+    fmt.Printf("Address:[0x%08x] is zero-fill, not present in file\n", addr)
+
+    return nil
+  } else if e == err.AddressNotMapped {
+    fmt.Printf("Address:[0x%08x] is not mapped by any segment\n", addr)
+
+    return nil
+  } else if e != nil {
+    return e
+  }
+
+  if p.Output == "json" {
+    misc.ShowJson(result)
+
+    return nil
+  }
+
+  for _, line := range result {
+    if len(line.File) > 0 {
+      fmt.Printf("%s:%d\t%s\n", line.File, line.Line, line.Source)
+    }
+
+    fmt.Printf("0x%08x:  %32v\t%-32v%s\n", line.Address, line.Instruction, line.Bytes, p.GetLineContent(line))
+  }
+
+  return nil
+}
@@ -0,0 +1,47 @@
+package misc
+
+import (
+  "fmt"
+  "strings"
+)
+
+// same layout of hex.Dump, but using the virtual address in the first column
+func Dump(addr uint64, data []byte) string {
+  var builder strings.Builder
+
+  for i:=0; i<len(data); i+=16 {
+    line := data[i:]
+
+    if len(line) > 16 {
+      line = line[:16]
+    }
+
+    builder.WriteString(fmt.Sprintf("%08x ", addr + uint64(i)))
+
+    for j:=0; j<16; j++ {
+      if j == 8 {
+        builder.WriteString(" ")
+      }
+
+      if j < len(line) {
+        builder.WriteString(fmt.Sprintf(" %02x", line[j]))
+      } else {
+        builder.WriteString("   ")
+      }
+    }
+
+    builder.WriteString("  |")
+
+    for _, b := range line {
+      if b >= 0x20 && b < 0x7f {
+        builder.WriteByte(b)
+      } else {
+        builder.WriteByte('.')
+      }
+    }
+
+    builder.WriteString("|\n")
+  }
+
+  return builder.String()
+}
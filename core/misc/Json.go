@@ -0,0 +1,18 @@
+package misc
+
+import (
+  "encoding/json"
+  "fmt"
+)
+
+func ShowJson(value interface{}) {
+  data, e := json.MarshalIndent(value, "", "  ")
+
+  if e != nil {
+    fmt.Println(e)
+
+    return
+  }
+
+  fmt.Println(string(data))
+}
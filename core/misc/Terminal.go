@@ -5,22 +5,43 @@ import (
     "os"
     "os/exec"
     "strconv"
+    "strings"
+    "syscall"
+    "unsafe"
 )
 
 type Term struct {
   active bool
+  saved string
 }
 
 func NewTerminal() *Term {
+  saved, _ := exec.Command("stty", "-F", "/dev/tty", "-g").Output()
+
+  term := &Term{
+    active: true, saved: strings.TrimSpace(string(saved))}
+
+  term.Resume()
+
+  return term
+}
+
+// gives the terminal back in the state it was found, while another program uses it
+func (p *Term) Suspend() {
+  if len(p.saved) > 0 {
+    exec.Command("stty", "-F", "/dev/tty", p.saved).Run()
+  } else {
+    exec.Command("stty", "-F", "/dev/tty", "echo", "icanon").Run()
+  }
+}
+
+func (p *Term) Resume() {
   exec.Command("stty", "-F", "/dev/tty", "cbreak", "min", "1").Run()
   exec.Command("stty", "-F", "/dev/tty", "-echo").Run()
-
-  return &Term{
-    active: true}
 }
 
 func (p *Term) Release() {
-  exec.Command("stty", "-F", "/dev/tty", "echo").Run()
+  p.Suspend()
 }
 
 func (p *Term) ClearScreen() {
@@ -47,3 +68,36 @@ func (p *Term) SetCursor(col, row int) {
   fmt.Println("\033[" + strconv.Itoa(row) + ";" + strconv.Itoa(col) + "H")
 }
 
+// allocates a new pseudo terminal, returning the master and the slave sides
+func OpenPty() (*os.File, *os.File, error) {
+  master, e := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+
+  if e != nil {
+    return nil, nil, e
+  }
+
+  var unlock int32
+  var number uint32
+
+  if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+    master.Close()
+
+    return nil, nil, errno
+  }
+
+  if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number))); errno != 0 {
+    master.Close()
+
+    return nil, nil, errno
+  }
+
+  slave, e := os.OpenFile("/dev/pts/" + strconv.Itoa(int(number)), os.O_RDWR | syscall.O_NOCTTY, 0)
+
+  if e != nil {
+    master.Close()
+
+    return nil, nil, e
+  }
+
+  return master, slave, nil
+}
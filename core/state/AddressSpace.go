@@ -0,0 +1,164 @@
+package state
+
+import (
+  "debug/elf"
+
+  "jelf/core/err"
+)
+
+// the memory of a running process
+type Memory interface {
+  ReadMemory(addr, length uint64) ([]byte, error)
+}
+
+// the symbols of the shared libraries of a running process
+type Libraries interface {
+  GetLibrarySymbol(addr uint64) (string, uint64, error)
+}
+
+type Mapping struct {
+  Address uint64
+  Offset uint64
+  FileSize uint64
+  MemorySize uint64
+  Flags elf.ProgFlag
+}
+
+func (p *State) LoadMappings() {
+  p.Mappings = nil
+
+  for _, prog := range p.File.Progs {
+    if prog.Type == elf.PT_LOAD {
+      p.Mappings = append(p.Mappings, Mapping{
+        Address: prog.Vaddr, Offset: prog.Off, FileSize: prog.Filesz, MemorySize: prog.Memsz, Flags: prog.Flags})
+    }
+  }
+}
+
+func (p *State) GetMapping(addr uint64) (*Mapping, error) {
+  for i, mapping := range p.Mappings {
+    if addr >= mapping.Address && addr < mapping.Address + mapping.MemorySize {
+      return &p.Mappings[i], nil
+    }
+  }
+
+  return nil, err.AddressNotMapped
+}
+
+func (p *State) VirtualToOffset(addr uint64) (uint64, error) {
+  if len(p.Mappings) == 0 { // relocatable objects have no segments, so addresses are file offsets
+    return addr, nil
+  }
+
+  mapping, e := p.GetMapping(addr)
+
+  if e != nil {
+    return 0, e
+  }
+
+  if addr >= mapping.Address + mapping.FileSize {
+    return 0, err.ZeroFill
+  }
+
+  return mapping.Offset + (addr - mapping.Address), nil
+}
+
+func (p *State) OffsetToVirtual(offset uint64) (uint64, error) {
+  if len(p.Mappings) == 0 {
+    return offset, nil
+  }
+
+  for _, mapping := range p.Mappings {
+    if offset >= mapping.Offset && offset < mapping.Offset + mapping.FileSize {
+      return mapping.Address + (offset - mapping.Offset), nil
+    }
+  }
+
+  return 0, err.AddressNotMapped
+}
+
+// returns the file backed bytes starting at addr, stopping at the end of the
+// segment or at the beginning of its zero-fill part. The live view reads the
+// memory of the process instead
+func (p *State) ReadVirtual(addr, length uint64) ([]byte, error) {
+  if p.Live && p.Memory != nil {
+    data, e := p.Memory.ReadMemory(addr, length)
+
+    if e != nil || len(data) == 0 {
+      return nil, err.AddressNotMapped
+    }
+
+    return data, nil
+  }
+
+  offset, e := p.VirtualToOffset(addr)
+
+  if e != nil {
+    return nil, e
+  }
+
+  if offset >= uint64(len(p.Data)) {
+    return nil, err.AddressNotMapped
+  }
+
+  // the length is clamped before the addition, which could wrap around
+  if length > uint64(len(p.Data)) - offset {
+    length = uint64(len(p.Data)) - offset
+  }
+
+  end := offset + length
+
+  if len(p.Mappings) > 0 {
+    mapping, _ := p.GetMapping(addr)
+
+    if limit := mapping.Offset + mapping.FileSize; end > limit {
+      end = limit
+    }
+  }
+
+  return p.Data[offset:end], nil
+}
+
+// the bytes of the segment in the file, the sizes of the headers are not
+// trusted so a segment out of the file has none
+func (p *State) GetSegmentData(prog *elf.Prog) ([]byte, error) {
+  if prog.Off > uint64(len(p.Data)) || prog.Filesz > uint64(len(p.Data)) - prog.Off {
+    return nil, err.AddressNotMapped
+  }
+
+  return p.Data[prog.Off:prog.Off + prog.Filesz], nil
+}
+
+func (p *State) IsZeroFill(addr uint64) bool {
+  _, e := p.VirtualToOffset(addr)
+
+  return e == err.ZeroFill
+}
+
+func (p *State) GetSectionFromAddress(addr uint64) (*elf.Section, error) {
+  for _, section := range p.File.Sections {
+    if section.Flags & elf.SHF_ALLOC != 0 && addr >= section.Addr && addr < section.Addr + section.Size {
+      return section, nil
+    }
+  }
+
+  return nil, err.SectionNotFound
+}
+
+// converts an address of the current view to the one of the file
+func (p *State) FileAddress(addr uint64) uint64 {
+  if p.Live && addr >= p.Bias {
+    return addr - p.Bias
+  }
+
+  return addr
+}
+
+// converts an address of the file to the one of the current view
+func (p *State) ViewAddress(addr uint64) uint64 {
+  if p.Live {
+    return addr + p.Bias
+  }
+
+  return addr
+}
@@ -0,0 +1,23 @@
+package state
+
+import (
+  "debug/elf"
+
+  "jelf/core/err"
+)
+
+type DynamicEntry struct {
+  Tag elf.DynTag
+  Value uint64
+  Text string
+}
+
+func (p *State) GetDynamic(tag elf.DynTag) (*DynamicEntry, error) {
+  for i, entry := range p.Dynamic {
+    if entry.Tag == tag {
+      return &p.Dynamic[i], nil
+    }
+  }
+
+  return nil, err.DynamicNotFound
+}
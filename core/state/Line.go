@@ -0,0 +1,112 @@
+package state
+
+import (
+  "io/ioutil"
+  "sort"
+  "strings"
+
+  "jelf/core/err"
+)
+
+// a row of the line table of the dwarf information, the end of a sequence
+// is not part of it
+type SourceLine struct {
+  Address uint64 `json:"address"`
+  File string `json:"file"`
+  Line int `json:"line"`
+  End bool `json:"-"`
+}
+
+// the index of the row of the file address, the lines are sorted by address
+func (p *State) getSourceIndex(addr uint64) int {
+  i := sort.Search(len(p.Lines), func(i int) bool {
+    return p.Lines[i].Address > addr
+  })
+
+  if i == 0 || p.Lines[i - 1].End {
+    return -1
+  }
+
+  return i - 1
+}
+
+func (p *State) GetSourceLine(addr uint64) (*SourceLine, error) {
+  i := p.getSourceIndex(addr)
+
+  if i < 0 {
+    return nil, err.LineNotFound
+  }
+
+  return &p.Lines[i], nil
+}
+
+// the range of the code of the line at the file address, the following rows of
+// the same line are part of it
+func (p *State) GetSourceRange(addr uint64) (*SourceLine, uint64, error) {
+  i := p.getSourceIndex(addr)
+
+  if i < 0 {
+    return nil, 0, err.LineNotFound
+  }
+
+  line := &p.Lines[i]
+
+  for i = i + 1; i < len(p.Lines) - 1; i++ {
+    if next := p.Lines[i]; next.End || next.Line != line.Line || next.File != line.File {
+      break
+    }
+  }
+
+  return line, p.Lines[i].Address, nil
+}
+
+// the lowest address of the line, or of the next one with code. The file is
+// its path or the end of it, like its name
+func (p *State) GetLineAddress(file string, line int) (uint64, error) {
+  found := false
+  best := SourceLine{}
+
+  for _, row := range p.Lines {
+    if row.End || (row.File != file && strings.HasSuffix(row.File, "/" + file) == false) || row.Line < line {
+      continue
+    }
+
+    if found == false || row.Line < best.Line || (row.Line == best.Line && row.Address < best.Address) {
+      best = row
+      found = true
+    }
+  }
+
+  if found == false {
+    return 0, err.LineNotFound
+  }
+
+  return best.Address, nil
+}
+
+// the text of a line of a source file, the files are read once
+func (p *State) GetSourceText(file string, line int) (string, error) {
+  if p.Sources == nil {
+    p.Sources = map[string][]string{}
+  }
+
+  lines, found := p.Sources[file]
+
+  if found == false {
+    if data, e := ioutil.ReadFile(file); e == nil {
+      lines = strings.Split(string(data), "\n")
+    }
+
+    p.Sources[file] = lines
+  }
+
+  if lines == nil {
+    return "", err.SourceNotFound
+  }
+
+  if line < 1 || line > len(lines) {
+    return "", err.LineNotFound
+  }
+
+  return strings.TrimRight(lines[line - 1], "\r"), nil
+}
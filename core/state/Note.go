@@ -0,0 +1,54 @@
+package state
+
+import (
+  "encoding/binary"
+  "strings"
+)
+
+type Note struct {
+  Name string
+  Type uint32
+  Desc []byte
+}
+
+// splits the entries of a note section or segment, padded to the alignment
+func ParseNotes(order binary.ByteOrder, data []byte, align uint64) []Note {
+  var notes []Note
+
+  if align != 8 {
+    align = 4
+  }
+
+  round := func(n uint64) uint64 {
+    return (n + align - 1) &^ (align - 1)
+  }
+
+  var offset uint64
+
+  for offset + 12 <= uint64(len(data)) {
+    namesz := uint64(order.Uint32(data[offset:]))
+    descsz := uint64(order.Uint32(data[offset + 4:]))
+    t := order.Uint32(data[offset + 8:])
+
+    offset = offset + 12
+
+    if offset + namesz > uint64(len(data)) {
+      break
+    }
+
+    name := strings.TrimRight(string(data[offset:offset + namesz]), "\x00")
+
+    offset = round(offset + namesz)
+
+    if offset + descsz > uint64(len(data)) {
+      break
+    }
+
+    notes = append(notes, Note{
+      Name: name, Type: t, Desc: data[offset:offset + descsz]})
+
+    offset = round(offset + descsz)
+  }
+
+  return notes
+}
@@ -0,0 +1,25 @@
+package state
+
+type Relocation struct {
+  Section string `json:"section"`
+  Address uint64 `json:"address"`
+  Offset uint64 `json:"offset"`
+  Type uint32 `json:"type"`
+  TypeName string `json:"type_name"`
+  Symbol string `json:"symbol"`
+  SymbolValue uint64 `json:"symbol_value"`
+  Addend int64 `json:"addend"`
+  HasAddend bool `json:"has_addend"`
+}
+
+func (p *State) GetRelocationsFromRange(addr, length uint64) []Relocation {
+  var relocations []Relocation
+
+  for _, relocation := range p.Relocations {
+    if relocation.Address >= addr && relocation.Address < addr + length {
+      relocations = append(relocations, relocation)
+    }
+  }
+
+  return relocations
+}
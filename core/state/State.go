@@ -1,6 +1,7 @@
 package state
 
 import (
+  "debug/dwarf"
   "debug/elf"
 )
 
@@ -10,8 +11,22 @@ type State struct {
   Symbols []elf.Symbol
   DynamicSymbols []elf.Symbol
   Sections []*elf.Section
+  Mappings []Mapping
   Data []byte
   Strings []string
+  Dynamic []DynamicEntry
+  Relocations []Relocation
+  PltSymbols map[uint64]string
+  Lines []SourceLine
+  Sources map[string][]string // the lines of the source files, nil when not on disk
+  Dwarf *dwarf.Data // once loaded, the types are cached by it
+  BitOffsets map[*dwarf.StructField]int64 // the offsets in bits of the bit fields of the types of Dwarf
+  Address uint64
+  Memory Memory
+  Libraries Libraries
+  Bias uint64
+  Live bool
+  Output string
   Analyzed bool
   Running bool
 }
@@ -1,26 +1,106 @@
 package main
 
 import (
+  "flag"
   "fmt"
+  "io/ioutil"
   "os"
   "log"
+  "strings"
 
   jelf "jelf/core"
 )
 
+func usage() {
+  fmt.Println("usage: ", os.Args[0], " [options] <binary>")
+  fmt.Println("       ", os.Args[0], " [options] <command> [args] <binary>")
+  fmt.Println("options:")
+
+  flag.PrintDefaults()
+
+  fmt.Println("commands: any command of the interactive mode (ex: info, sections, symbols, strings, disassemble, dump, checksec)")
+}
+
 func main() {
-  if len(os.Args) != 2 {
-    fmt.Println("usage: ", os.Args[0], " <binary>")
+  commands := flag.String("c", "", "run the commands separated by ';' and exit")
+  script := flag.String("x", "", "run the commands of a script file and exit")
+  seek := flag.String("s", "", "seek to the address, symbol or section before running the commands")
+  json := flag.Bool("json", false, "print the results as json")
+
+  flag.Usage = usage
+  flag.Parse()
+
+  args := flag.Args()
+
+  if len(args) == 0 {
+    usage()
 
     return
   }
 
-  analyzer, err := jelf.NewAnalyzer(os.Args[1])
+  analyzer, err := jelf.NewAnalyzer(args[len(args) - 1])
 
   if err != nil {
     log.Fatal(err)
   }
 
-  analyzer.Process()
-}
+  if *json {
+    analyzer.Output = "json"
+  }
+
+  batch := ""
+
+  if len(args) > 1 {
+    if jelf.IsCommand(args[0]) == false {
+      log.Fatal("unknown command: ", args[0])
+    }
+
+    batch = strings.Join(args[:len(args) - 1], " ")
+  }
+
+  if len(batch) == 0 && len(*commands) == 0 && len(*script) == 0 {
+    // the symbols are needed to resolve the address, like in batch mode
+    if len(*seek) > 0 {
+      analyzer.Analyze()
+      analyzer.Execute("seek " + *seek)
+    }
+
+    analyzer.Process()
+
+    return
+  }
+
+  analyzer.Analyze()
+
+  if len(*seek) > 0 {
+    if analyzer.Execute("seek " + *seek) != nil {
+      os.Exit(1)
+    }
+  }
+
+  failed := false
+
+  if len(*script) > 0 {
+    data, err := ioutil.ReadFile(*script)
+
+    if err != nil {
+      log.Fatal(err)
+    }
 
+    if analyzer.ExecuteScript(string(data)) != nil {
+      failed = true
+    }
+  }
+
+  if len(*commands) > 0 && analyzer.ExecuteScript(*commands) != nil {
+    failed = true
+  }
+
+  if len(batch) > 0 && analyzer.Execute(batch) != nil {
+    failed = true
+  }
+
+  if failed {
+    os.Exit(1)
+  }
+}